@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	connectorSchema "github.com/conductorone/baton-redis/pkg/connector"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// readSecurityBaseline returns the baseline the security posture of the
+// clusters is checked against: the default one, overridden by the settings
+// of the security baseline file when set.
+func readSecurityBaseline(v *viper.Viper) (connectorSchema.SecurityBaseline, error) {
+	baseline := connectorSchema.DefaultSecurityBaseline
+
+	path := v.GetString(securityBaselineFileField.FieldName)
+	if path == "" {
+		return baseline, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return baseline, fmt.Errorf("invalid %s: %w", securityBaselineFileField.FieldName, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&baseline); err != nil && !errors.Is(err, io.EOF) {
+		return baseline, fmt.Errorf("invalid %s %s: %w", securityBaselineFileField.FieldName, path, err)
+	}
+	if err := baseline.Validate(); err != nil {
+		return baseline, fmt.Errorf("invalid %s %s: %w", securityBaselineFileField.FieldName, path, err)
+	}
+
+	return baseline, nil
+}
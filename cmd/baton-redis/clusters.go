@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// clustersFile is the file listing the clusters to sync, see
+// clustersFileField. JSON being valid YAML, it can be written in either.
+type clustersFile struct {
+	Clusters []clusterConfig `yaml:"clusters"`
+}
+
+// clusterConfig is a cluster of the clusters file. Empty credentials and API
+// port default to the ones set through flags.
+type clusterConfig struct {
+	// ID prefixes the resource ids of the objects of the cluster, it must be
+	// unique and stable across syncs.
+	ID            string      `yaml:"id"`
+	Host          string      `yaml:"host"`
+	APIPort       string      `yaml:"api_port"`
+	Username      string      `yaml:"username"`
+	Password      string      `yaml:"password"`
+	PasswordEnv   string      `yaml:"password_env"`
+	Nodes         []string    `yaml:"nodes"`
+	DiscoverNodes bool        `yaml:"discover_nodes"`
+	TLS           tlsSettings `yaml:"tls"`
+}
+
+// tlsSettings are the TLS settings used to reach a cluster, the defaults of
+// the system when empty.
+type tlsSettings struct {
+	// CAFile is a PEM file of the certificates trusted to sign the cluster
+	// certificate, instead of the system ones.
+	CAFile             string `yaml:"ca_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// readClusters returns the clusters to sync: those of the clusters file when
+// set, otherwise the one configured through flags, with no ID. Hosts and nodes
+// are normalized and credentials resolved.
+func readClusters(v *viper.Viper) ([]clusterConfig, error) {
+	path := v.GetString(clustersFileField.FieldName)
+	if path == "" {
+		apiPort := v.GetString(apiPortField.FieldName)
+		host, err := client.NormalizeEndpoint(v.GetString(clusterHostField.FieldName), apiPort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", clusterHostField.FieldName, err)
+		}
+		nodes, err := normalizeNodes(host, apiPort, v.GetStringSlice(clusterNodesField.FieldName))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", clusterNodesField.FieldName, err)
+		}
+
+		return []clusterConfig{{
+			Host:          host,
+			APIPort:       apiPort,
+			Username:      v.GetString(usernameField.FieldName),
+			Password:      v.GetString(passwordField.FieldName),
+			Nodes:         nodes,
+			DiscoverNodes: v.GetBool(discoverNodesField.FieldName),
+		}}, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", clustersFileField.FieldName, err)
+	}
+
+	var file clustersFile
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid %s %s: %w", clustersFileField.FieldName, path, err)
+	}
+	if len(file.Clusters) == 0 {
+		return nil, fmt.Errorf("invalid %s %s: no cluster listed", clustersFileField.FieldName, path)
+	}
+
+	seen := make(map[string]bool, len(file.Clusters))
+	for i := range file.Clusters {
+		cluster := &file.Clusters[i]
+		if err := cluster.resolve(v); err != nil {
+			return nil, fmt.Errorf("invalid %s %s: cluster %d %s: %w", clustersFileField.FieldName, path, i+1, cluster.ID, err)
+		}
+		if seen[cluster.ID] {
+			return nil, fmt.Errorf("invalid %s %s: duplicate cluster id %s", clustersFileField.FieldName, path, cluster.ID)
+		}
+		seen[cluster.ID] = true
+	}
+
+	// The db cache backend is a single file keyed by path, clusters would read
+	// each other's responses.
+	if len(file.Clusters) > 1 && uhttp.NewCacheConfigFromEnv().Backend == uhttp.CacheBackendDB {
+		return nil, fmt.Errorf("the db HTTP cache backend can't be shared by several clusters, unset BATON_HTTP_CACHE_BACKEND or set it to memory")
+	}
+
+	return file.Clusters, nil
+}
+
+// resolve checks a cluster of the clusters file, filling in the defaults set
+// through flags.
+func (c *clusterConfig) resolve(v *viper.Viper) error {
+	switch {
+	case c.ID == "":
+		return fmt.Errorf("missing id")
+	case strings.Contains(c.ID, "/"):
+		return fmt.Errorf("id %q must not contain /", c.ID)
+	case c.Host == "":
+		return fmt.Errorf("missing host")
+	}
+
+	if c.APIPort == "" {
+		c.APIPort = v.GetString(apiPortField.FieldName)
+	}
+	host, err := client.NormalizeEndpoint(c.Host, c.APIPort)
+	if err != nil {
+		return fmt.Errorf("invalid host: %w", err)
+	}
+	c.Host = host
+
+	c.Nodes, err = normalizeNodes(host, c.APIPort, c.Nodes)
+	if err != nil {
+		return fmt.Errorf("invalid nodes: %w", err)
+	}
+
+	if c.Username == "" {
+		c.Username = v.GetString(usernameField.FieldName)
+	}
+	if c.PasswordEnv != "" {
+		if c.Password != "" {
+			return fmt.Errorf("password and password_env are mutually exclusive")
+		}
+		c.Password = os.Getenv(c.PasswordEnv)
+		if c.Password == "" {
+			return fmt.Errorf("environment variable %s is empty", c.PasswordEnv)
+		}
+	}
+	if c.Password == "" {
+		c.Password = v.GetString(passwordField.FieldName)
+	}
+	if c.Username == "" || c.Password == "" {
+		return fmt.Errorf("missing username or password, and no default set through flags")
+	}
+
+	_, err = c.TLS.config()
+	return err
+}
+
+// config returns the TLS configuration of the settings, nil for the defaults.
+func (s tlsSettings) config() (*tls.Config, error) {
+	if s == (tlsSettings{}) {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         s.ServerName,
+		InsecureSkipVerify: s.InsecureSkipVerify, //nolint:gosec // Opted into per cluster, for self-signed certificates.
+	}
+
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls ca_file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid tls ca_file %s: no PEM certificate found", s.CAFile)
+		}
+	}
+
+	return config, nil
+}
+
+// normalizeNodes normalizes the failover nodes of the cluster at host. Nodes
+// listed without a scheme use the one of host.
+func normalizeNodes(host, apiPort string, nodes []string) ([]string, error) {
+	scheme, _, _ := strings.Cut(host, "://")
+
+	var normalized []string
+	for _, node := range nodes {
+		node = strings.TrimSpace(node)
+		if node == "" {
+			continue
+		}
+		if !strings.Contains(node, "://") {
+			node = scheme + "://" + node
+		}
+		endpoint, err := client.NormalizeEndpoint(node, apiPort)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, endpoint)
+	}
+
+	return normalized, nil
+}
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-redis/pkg/audit"
+	connectorSchema "github.com/conductorone/baton-redis/pkg/connector"
 	"github.com/conductorone/baton-sdk/pkg/field"
 	"github.com/spf13/viper"
 )
@@ -8,40 +13,209 @@ import (
 var (
 	clusterHostField = field.StringField(
 		"cluster-host",
-		field.WithDescription("The enterprise cluster host"),
-		field.WithRequired(true),
+		field.WithDescription("The enterprise cluster host, optionally with a scheme, port and path prefix"),
+	)
+	clustersFileField = field.StringField(
+		"clusters-file",
+		field.WithDescription("YAML or JSON file listing the clusters to sync, instead of cluster-host; username, password and api-port are the defaults of its entries"),
 	)
 	apiPortField = field.StringField(
 		"api-port",
 		field.WithDescription("The enterprise API port"),
 		field.WithDefaultValue("9443"),
 	)
+	clusterNodesField = field.StringSliceField(
+		"cluster-nodes",
+		field.WithDescription("Additional cluster node hosts to fail over to when the cluster host is unavailable"),
+	)
+	discoverNodesField = field.BoolField(
+		"discover-nodes",
+		field.WithDescription("Discover the cluster nodes to fail over to from the cluster API"),
+	)
+	maxRetriesField = field.IntField(
+		"max-retries",
+		field.WithDescription("How many times a request failing with a transient cluster error is retried, 0 disables retries"),
+		field.WithDefaultValue(3),
+	)
+	retryInitialBackoffField = field.StringField(
+		"retry-initial-backoff",
+		field.WithDescription("How long to wait before the first retry, doubled on every following retry"),
+		field.WithDefaultValue("500ms"),
+	)
+	retryMaxBackoffField = field.StringField(
+		"retry-max-backoff",
+		field.WithDescription("The longest time to wait between two retries"),
+		field.WithDefaultValue("30s"),
+	)
+	incrementalSyncMaxAgeField = field.StringField(
+		"incremental-sync-max-age",
+		field.WithDescription("How old the previous sync can be for the next one to only fetch the objects the cluster event log reports as changed, 0 always syncs everything. Defaults to 24h with --incremental-sync-state-dir and 0 without it"),
+	)
+	incrementalSyncStateDirField = field.StringField(
+		"incremental-sync-state-dir",
+		field.WithDescription("Directory the position of a sync in the cluster event log and the objects it read are saved to, for the next run to sync incrementally"),
+	)
+	auditListenField = field.StringField(
+		"audit-listen",
+		field.WithDescription("Address to receive database connection audit records on, tcp://host:port or unix:///path/to/socket, reported as events"),
+	)
+	auditBufferSizeField = field.IntField(
+		"audit-buffer-size",
+		field.WithDescription("How many received audit records are kept until they are read as events"),
+		field.WithDefaultValue(audit.DefaultCapacity),
+	)
+	securityBaselineFileField = field.StringField(
+		"security-baseline-file",
+		field.WithDescription("YAML or JSON file overriding the settings of the security baseline the cluster posture is checked against"),
+	)
+	dormantAccountDaysField = field.IntField(
+		"dormant-account-days",
+		field.WithDescription("How many days a user can go without signing in before it is flagged as dormant, 0 disables the check"),
+		field.WithDefaultValue(connectorSchema.DefaultAccountThresholds.DormantDays),
+	)
+	stalePasswordDaysField = field.IntField(
+		"stale-password-days",
+		field.WithDescription("How many days a user can keep its password before it is flagged as stale, 0 disables the check"),
+		field.WithDefaultValue(connectorSchema.DefaultAccountThresholds.StalePasswordDays),
+	)
+	recordFixturesField = field.StringField(
+		"record-fixtures",
+		field.WithDescription("Directory to record sanitized cluster API requests and responses to, for use as test fixtures"),
+		field.WithHidden(true),
+	)
 	usernameField = field.StringField(
 		"username",
 		field.WithDescription("The enterprise cluster admin username"),
-		field.WithRequired(true),
 	)
 	passwordField = field.StringField(
 		"password",
 		field.WithDescription("The enterprise cluster admin password"),
-		field.WithRequired(true),
 	)
 	// ConfigurationFields defines the external configuration required for the
 	// connector to run. Note: these fields can be marked as optional or
 	// required.
-	ConfigurationFields = []field.SchemaField{clusterHostField, apiPortField, usernameField, passwordField}
+	ConfigurationFields = []field.SchemaField{
+		clusterHostField,
+		clustersFileField,
+		apiPortField,
+		clusterNodesField,
+		discoverNodesField,
+		maxRetriesField,
+		retryInitialBackoffField,
+		retryMaxBackoffField,
+		incrementalSyncMaxAgeField,
+		incrementalSyncStateDirField,
+		auditListenField,
+		auditBufferSizeField,
+		securityBaselineFileField,
+		dormantAccountDaysField,
+		stalePasswordDaysField,
+		recordFixturesField,
+		usernameField,
+		passwordField,
+	}
 
 	// FieldRelationships defines relationships between the fields listed in
 	// ConfigurationFields that can be automatically validated. For example, a
 	// username and password can be required together, or an access token can be
 	// marked as mutually exclusive from the username password pair.
-	FieldRelationships = []field.SchemaFieldRelationship{}
+	FieldRelationships = []field.SchemaFieldRelationship{
+		field.FieldsAtLeastOneUsed(clusterHostField, clustersFileField),
+		field.FieldsMutuallyExclusive(clusterHostField, clustersFileField),
+		field.FieldsDependentOn(
+			[]field.SchemaField{clusterHostField},
+			[]field.SchemaField{usernameField, passwordField},
+		),
+	}
 )
 
 // ValidateConfig is run after the configuration is loaded, and should return an
 // error if it isn't valid. Implementing this function is optional, it only
 // needs to perform extra validations that cannot be encoded with configuration
 // parameters.
+//
+// The cluster host and nodes, or the clusters file, and the security baseline
+// file are read and checked. The configuration is left as is: the endpoints are
+// normalized by readClusters when the clients are built.
 func ValidateConfig(v *viper.Viper) error {
+	if _, err := readClusters(v); err != nil {
+		return err
+	}
+
+	if v.GetInt(maxRetriesField.FieldName) < 0 {
+		return fmt.Errorf("invalid %s: must not be negative", maxRetriesField.FieldName)
+	}
+
+	initialBackoff, err := parseDuration(v, retryInitialBackoffField)
+	if err != nil {
+		return err
+	}
+	maxBackoff, err := parseDuration(v, retryMaxBackoffField)
+	if err != nil {
+		return err
+	}
+	if initialBackoff > 0 && maxBackoff > 0 && initialBackoff > maxBackoff {
+		return fmt.Errorf("invalid %s: must not exceed %s", retryInitialBackoffField.FieldName, retryMaxBackoffField.FieldName)
+	}
+
+	if _, err := incrementalMaxAge(v); err != nil {
+		return err
+	}
+
+	if address := v.GetString(auditListenField.FieldName); address != "" {
+		if _, _, err := audit.ParseAddress(address); err != nil {
+			return fmt.Errorf("invalid %s: %w", auditListenField.FieldName, err)
+		}
+	}
+	if v.GetInt(auditBufferSizeField.FieldName) < 0 {
+		return fmt.Errorf("invalid %s: must not be negative", auditBufferSizeField.FieldName)
+	}
+
+	if _, err := readSecurityBaseline(v); err != nil {
+		return err
+	}
+
+	for _, f := range []field.SchemaField{dormantAccountDaysField, stalePasswordDaysField} {
+		if v.GetInt(f.FieldName) < 0 {
+			return fmt.Errorf("invalid %s: must not be negative", f.FieldName)
+		}
+	}
+
 	return nil
 }
+
+// defaultIncrementalMaxAge is how old the previous sync can be for an
+// incremental one when a state directory is configured and no max age is set.
+const defaultIncrementalMaxAge = 24 * time.Hour
+
+// incrementalMaxAge reads the incremental sync max age. Without a state
+// directory the watermark only lives as long as the process, so incremental
+// sync stays off unless it is asked for.
+func incrementalMaxAge(v *viper.Viper) (time.Duration, error) {
+	if v.GetString(incrementalSyncMaxAgeField.FieldName) == "" {
+		if v.GetString(incrementalSyncStateDirField.FieldName) != "" {
+			return defaultIncrementalMaxAge, nil
+		}
+		return 0, nil
+	}
+	return parseDuration(v, incrementalSyncMaxAgeField)
+}
+
+// parseDuration reads a duration field, an empty value leaves the client's
+// default in place.
+func parseDuration(v *viper.Viper, f field.SchemaField) (time.Duration, error) {
+	value := v.GetString(f.FieldName)
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", f.FieldName, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s: must not be negative", f.FieldName)
+	}
+
+	return d, nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/conductorone/baton-redis/pkg/audit"
 	"github.com/conductorone/baton-redis/pkg/client"
 	connectorSchema "github.com/conductorone/baton-redis/pkg/connector"
 	"github.com/conductorone/baton-sdk/pkg/config"
@@ -26,7 +28,8 @@ func main() {
 		"baton-redis",
 		getConnector,
 		field.Configuration{
-			Fields: ConfigurationFields,
+			Fields:      ConfigurationFields,
+			Constraints: FieldRelationships,
 		},
 	)
 	if err != nil {
@@ -46,17 +49,57 @@ func main() {
 func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
 
-	clusterHost := v.GetString(clusterHostField.FieldName)
-	username := v.GetString(usernameField.FieldName)
-	password := v.GetString(passwordField.FieldName)
-	apiPort := v.GetString(apiPortField.FieldName)
-
-	redisClient := client.NewClient(username, password, clusterHost, apiPort)
 	if err := ValidateConfig(v); err != nil {
 		return nil, err
 	}
 
-	connectorBuilder, err := connectorSchema.New(ctx, redisClient)
+	clusterConfigs, err := readClusters(v)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]connectorSchema.ClusterClient, 0, len(clusterConfigs))
+	for _, cc := range clusterConfigs {
+		redisClient := client.NewClient(cc.Username, cc.Password, cc.Host, cc.APIPort)
+		redisClient.NodeHosts = cc.Nodes
+		redisClient.DiscoverNodes = cc.DiscoverNodes
+		// The settings were checked by ValidateConfig.
+		redisClient.TLSConfig, _ = cc.TLS.config()
+		redisClient.MaxRetries = v.GetInt(maxRetriesField.FieldName)
+		redisClient.InitialBackoff, _ = parseDuration(v, retryInitialBackoffField)
+		redisClient.MaxBackoff, _ = parseDuration(v, retryMaxBackoffField)
+		redisClient.IncrementalMaxAge, _ = incrementalMaxAge(v)
+		if dir := v.GetString(incrementalSyncStateDirField.FieldName); dir != "" {
+			redisClient.IncrementalStateFile = filepath.Join(dir, cc.ID, "incremental-sync.json")
+		}
+		if dir := v.GetString(recordFixturesField.FieldName); dir != "" {
+			redisClient.FixtureDir = filepath.Join(dir, cc.ID)
+		}
+
+		clusters = append(clusters, connectorSchema.ClusterClient{ID: cc.ID, Client: redisClient})
+	}
+
+	// The baseline was checked by ValidateConfig.
+	baseline, _ := readSecurityBaseline(v)
+	connectorOpts := []connectorSchema.Option{
+		connectorSchema.WithSecurityBaseline(baseline),
+		connectorSchema.WithAccountThresholds(connectorSchema.AccountThresholds{
+			DormantDays:       v.GetInt(dormantAccountDaysField.FieldName),
+			StalePasswordDays: v.GetInt(stalePasswordDaysField.FieldName),
+		}),
+	}
+	if address := v.GetString(auditListenField.FieldName); address != "" {
+		// The receiver runs for as long as the connector does.
+		receiver, err := audit.Listen(ctx, address, v.GetInt(auditBufferSizeField.FieldName))
+		if err != nil {
+			l.Error("error starting audit receiver", zap.Error(err))
+			return nil, err
+		}
+		l.Info("receiving database connection audit records", zap.String("address", receiver.Addr().String()))
+		connectorOpts = append(connectorOpts, connectorSchema.WithAuditReceiver(receiver))
+	}
+
+	connectorBuilder, err := connectorSchema.NewClusters(ctx, clusters, v.GetBool("provisioning"), connectorOpts...)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
@@ -0,0 +1,274 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// DefaultCapacity is how many records a Receiver keeps by default.
+const DefaultCapacity = 10000
+
+// maxSeenIPs is how many client IPs a Receiver remembers, by user. Once there
+// are more, those of the least recently seen are forgotten and reported as
+// first seen again when they come back.
+const maxSeenIPs = 100000
+
+// Entry is a record kept by a Receiver.
+type Entry struct {
+	// Seq numbers the records a Receiver received, starting at 1.
+	Seq    uint64
+	Record Record
+	// FirstSeenIP is set on the first successful authentication of a user
+	// from its source IP.
+	FirstSeenIP bool
+}
+
+// Receiver listens for the audit records of a cluster and keeps the latest
+// ones in memory, in a ring buffer. Nothing is persisted: a new Receiver has a
+// new Epoch and starts from an empty buffer, with no client IP seen yet.
+type Receiver struct {
+	// Epoch tells the records of this Receiver from those of a previous run.
+	Epoch string
+
+	listener net.Listener
+	capacity int
+
+	mu sync.Mutex
+	// entries holds up to capacity entries, the oldest at head once full.
+	entries []Entry
+	head    int
+	next    uint64
+	seenIPs *seenIPs
+	conns   map[net.Conn]bool
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// Listen starts a Receiver on address, either tcp://host:port or
+// unix:///path/to/socket, keeping up to capacity records.
+func Listen(ctx context.Context, address string, capacity int) (*Receiver, error) {
+	network, addr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+
+	if network == "unix" {
+		// A socket left behind by a previous run would make Listen fail.
+		if info, err := os.Stat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(addr)
+		}
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("baton-redis: unable to listen for audit records on %s: %w", address, err)
+	}
+
+	r := &Receiver{
+		Epoch:    strconv.FormatInt(time.Now().UnixNano(), 36),
+		listener: listener,
+		capacity: capacity,
+		next:     1,
+		seenIPs:  newSeenIPs(maxSeenIPs),
+		conns:    make(map[net.Conn]bool),
+	}
+
+	r.wg.Add(1)
+	go r.accept(ctx)
+
+	return r, nil
+}
+
+// ParseAddress splits a listener address into the network and address
+// net.Listen expects.
+func ParseAddress(address string) (string, string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", fmt.Errorf("baton-redis: invalid audit listener address %q: %w", address, err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		if u.Host == "" || u.Port() == "" {
+			return "", "", fmt.Errorf("baton-redis: audit listener address %q must be tcp://host:port", address)
+		}
+		return "tcp", u.Host, nil
+	case "unix":
+		path := u.Path
+		if u.Host != "" {
+			path = u.Host + u.Path
+		}
+		if path == "" {
+			return "", "", fmt.Errorf("baton-redis: audit listener address %q must be unix:///path/to/socket", address)
+		}
+		return "unix", path, nil
+	default:
+		return "", "", fmt.Errorf("baton-redis: audit listener address %q must start with tcp:// or unix://", address)
+	}
+}
+
+// Addr returns the address the Receiver listens on.
+func (r *Receiver) Addr() net.Addr {
+	return r.listener.Addr()
+}
+
+// Close stops listening and drops the open connections.
+func (r *Receiver) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	for conn := range r.conns {
+		_ = conn.Close()
+	}
+	r.mu.Unlock()
+
+	err := r.listener.Close()
+	r.wg.Wait()
+	return err
+}
+
+// Since returns up to limit entries received after seq, and whether there are
+// more. Entries of another epoch are read from the oldest one kept. When the
+// buffer overflowed since seq, the entries dropped are skipped.
+func (r *Receiver) Since(epoch string, seq uint64, limit int) ([]Entry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if epoch != r.Epoch {
+		seq = 0
+	}
+
+	start := 0
+	if len(r.entries) > 0 && seq >= r.at(0).Seq {
+		start = int(seq - r.at(0).Seq + 1) //nolint:gosec // seq is within the buffer.
+	}
+	if start > len(r.entries) {
+		start = len(r.entries)
+	}
+
+	end := len(r.entries)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+
+	res := make([]Entry, 0, end-start)
+	for i := start; i < end; i++ {
+		res = append(res, r.at(i))
+	}
+	return res, end < len(r.entries)
+}
+
+// at returns the i-th oldest entry kept.
+func (r *Receiver) at(i int) Entry {
+	return r.entries[(r.head+i)%len(r.entries)]
+}
+
+// Last returns the sequence number of the last record received, 0 if none.
+func (r *Receiver) Last() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.next - 1
+}
+
+func (r *Receiver) accept(ctx context.Context) {
+	defer r.wg.Done()
+	l := ctxzap.Extract(ctx)
+
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.Error("baton-redis: audit listener stopped", zap.Error(err))
+			}
+			return
+		}
+
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		r.conns[conn] = true
+		r.mu.Unlock()
+
+		r.wg.Add(1)
+		go r.serve(ctx, conn)
+	}
+}
+
+// serve reads the records sent on conn. The cluster writes one JSON object per
+// record; they are decoded as a stream so that any whitespace or none at all
+// may separate them.
+func (r *Receiver) serve(ctx context.Context, conn net.Conn) {
+	defer r.wg.Done()
+	defer func() {
+		r.mu.Lock()
+		delete(r.conns, conn)
+		r.mu.Unlock()
+		_ = conn.Close()
+	}()
+	l := ctxzap.Extract(ctx)
+
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	decoder.UseNumber()
+
+	for {
+		var fields map[string]any
+		err := decoder.Decode(&fields)
+		if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			// The stream can't be resynchronized after a malformed record.
+			l.Warn("baton-redis: dropping audit connection after invalid record", zap.Error(err))
+			return
+		}
+
+		record, err := recordFromFields(fields)
+		if err != nil {
+			l.Warn("baton-redis: skipping audit record", zap.Error(err))
+			continue
+		}
+		r.add(record)
+	}
+}
+
+// add keeps record, dropping the oldest entry when the buffer is full.
+func (r *Receiver) add(record Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := Entry{Seq: r.next, Record: record}
+	r.next++
+
+	if record.AuthenticationSucceeded() && record.SourceIP != "" {
+		user := record.Identity
+		if user == "" {
+			user = record.Username
+		}
+		entry.FirstSeenIP = r.seenIPs.add(user, record.SourceIP)
+	}
+
+	if len(r.entries) < r.capacity {
+		r.entries = append(r.entries, entry)
+		return
+	}
+	r.entries[r.head] = entry
+	r.head = (r.head + 1) % r.capacity
+}
@@ -0,0 +1,187 @@
+package audit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-redis/pkg/audit"
+	"github.com/conductorone/baton-redis/test"
+)
+
+func TestParseRecord(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		action  string
+		bdbUID  string
+		status  int
+		failed  bool
+		invalid bool
+	}{
+		{
+			name:   "new connection",
+			data:   `{"ts":1655821384,"new_conn":{"id":2285001002,"srcip":"127.0.0.1","srcp":"39338","bdb_name":"DB1","bdb_uid":"5"}}`,
+			action: audit.ActionNewConn,
+			bdbUID: "5",
+			status: -1,
+		},
+		{
+			name:   "failed authentication",
+			data:   `{"ts":1655821384,"action":"auth","id":2285001002,"srcip":"127.0.0.1","bdb_uid":5,"status":0,"username":"user_one"}`,
+			action: audit.ActionAuth,
+			bdbUID: "5",
+			status: audit.AuthFailed,
+			failed: true,
+		},
+		{
+			name:   "authentication not required",
+			data:   `{"ts":1655821384,"action":"auth","id":1,"bdb_uid":"5","status":2}`,
+			action: audit.ActionAuth,
+			bdbUID: "5",
+			status: audit.AuthNotRequired,
+		},
+		{name: "no action", data: `{"ts":1655821384,"id":1}`, invalid: true},
+		{name: "no timestamp", data: `{"action":"auth","status":8}`, invalid: true},
+		{name: "auth without status", data: `{"ts":1655821384,"action":"auth"}`, invalid: true},
+		{name: "not json", data: `ts=1655821384`, invalid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			record, err := audit.ParseRecord([]byte(tc.data))
+			if tc.invalid {
+				if err == nil {
+					t.Fatalf("Expected an error, got %+v", record)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if record.Action != tc.action || record.BdbUID != tc.bdbUID || record.Status != tc.status {
+				t.Errorf("Expected %s on %s with status %d, got %s on %s with status %d",
+					tc.action, tc.bdbUID, tc.status, record.Action, record.BdbUID, record.Status)
+			}
+			if record.AuthenticationFailed() != tc.failed {
+				t.Errorf("Expected failed authentication %v", tc.failed)
+			}
+			if !record.Time.Equal(time.Unix(1655821384, 0)) {
+				t.Errorf("Expected the record time, got %v", record.Time)
+			}
+		})
+	}
+}
+
+func TestReceiver(t *testing.T) {
+	// Unix socket paths are limited to about 100 bytes, too few for t.TempDir.
+	dir, err := os.MkdirTemp("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	for _, address := range []string{"tcp://127.0.0.1:0", "unix://" + filepath.Join(dir, "audit.sock")} {
+		t.Run(address, func(t *testing.T) {
+			receiver, err := audit.Listen(context.Background(), address, 0)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			t.Cleanup(func() { _ = receiver.Close() })
+
+			test.ReplayAudit(t, receiver, test.AuditFramesFile)
+
+			entries, more := receiver.Since("", 0, 0)
+			if more || len(entries) != 7 {
+				t.Fatalf("Expected 7 entries, got %d", len(entries))
+			}
+
+			var firstSeen []uint64
+			for i, entry := range entries {
+				if entry.Seq != uint64(i+1) {
+					t.Errorf("Expected entry %d to have sequence number %d, got %d", i, i+1, entry.Seq)
+				}
+				if entry.FirstSeenIP {
+					firstSeen = append(firstSeen, entry.Seq)
+				}
+			}
+			if len(firstSeen) != 2 || firstSeen[0] != 2 || firstSeen[1] != 6 {
+				t.Errorf("Expected the client IPs to be first seen by records 2 and 6, got %v", firstSeen)
+			}
+
+			entries, more = receiver.Since(receiver.Epoch, 3, 2)
+			if !more || len(entries) != 2 || entries[0].Seq != 4 {
+				t.Errorf("Expected records 4 and 5 and more, got %d entries", len(entries))
+			}
+
+			// A cursor of another run of the receiver starts over.
+			if entries, _ := receiver.Since("previous", 7, 0); len(entries) != 7 {
+				t.Errorf("Expected all entries for another epoch, got %d", len(entries))
+			}
+		})
+	}
+}
+
+func TestReceiver_Overflow(t *testing.T) {
+	receiver, err := audit.Listen(context.Background(), "tcp://127.0.0.1:0", 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { _ = receiver.Close() })
+
+	test.ReplayAudit(t, receiver, test.AuditFramesFile)
+
+	// Records 1 to 4 were dropped, a cursor on one of them resumes at 5.
+	entries, _ := receiver.Since(receiver.Epoch, 2, 0)
+	if len(entries) != 3 || entries[0].Seq != 5 {
+		t.Fatalf("Expected records 5 to 7, got %d entries", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Seq != uint64(i+5) {
+			t.Errorf("Expected entry %d to have sequence number %d, got %d", i, i+5, entry.Seq)
+		}
+	}
+
+	entries, more := receiver.Since(receiver.Epoch, 5, 1)
+	if !more || len(entries) != 1 || entries[0].Seq != 6 {
+		t.Errorf("Expected record 6 and more, got %d entries", len(entries))
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	testCases := []struct {
+		address string
+		network string
+		addr    string
+	}{
+		{address: "tcp://0.0.0.0:5514", network: "tcp", addr: "0.0.0.0:5514"},
+		{address: "tcp://[::1]:5514", network: "tcp", addr: "[::1]:5514"},
+		{address: "unix:///var/run/audit.sock", network: "unix", addr: "/var/run/audit.sock"},
+		{address: "unix://audit.sock", network: "unix", addr: "audit.sock"},
+		{address: "tcp://0.0.0.0"},
+		{address: "udp://0.0.0.0:5514"},
+		{address: "0.0.0.0:5514"},
+		{address: "unix://"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.address, func(t *testing.T) {
+			network, addr, err := audit.ParseAddress(tc.address)
+			if tc.network == "" {
+				if err == nil {
+					t.Fatalf("Expected an error, got %s %s", network, addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if network != tc.network || addr != tc.addr {
+				t.Errorf("Expected %s %s, got %s %s", tc.network, tc.addr, network, addr)
+			}
+		})
+	}
+}
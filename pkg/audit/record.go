@@ -0,0 +1,152 @@
+// Package audit receives the database connection audit records a Redis
+// Enterprise cluster streams to a listener configured through
+// /v1/cluster/auditing/db_conns, see
+// https://redis.io/docs/latest/operate/rs/security/audit-events/.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Audit record actions.
+const (
+	ActionNewConn   = "new_conn"
+	ActionCloseConn = "close_conn"
+	ActionAuth      = "auth"
+)
+
+// Authentication statuses reported by auth records.
+const (
+	AuthFailed              = 0
+	AuthFailedTooLong       = 1
+	AuthNotRequired         = 2
+	AuthDirectoryPending    = 3
+	AuthDirectoryError      = 4
+	AuthSyncerInProgress    = 5
+	AuthSyncerFailed        = 6
+	AuthSyncerOK            = 7
+	AuthOK                  = 8
+	authStatusNotApplicable = -1
+)
+
+// Record is a database connection audit record.
+type Record struct {
+	Time       time.Time
+	Action     string
+	ConnID     string
+	SourceIP   string
+	SourcePort string
+	TargetIP   string
+	TargetPort string
+	Hostname   string
+	BdbName    string
+	BdbUID     string
+	// Status is the authentication status of auth records, -1 for the others.
+	Status   int
+	Username string
+	// Identity is the cluster object the client authenticated as, such as
+	// "user:1".
+	Identity string
+	// Fields holds every field of the record as received.
+	Fields map[string]any
+}
+
+// AuthenticationFailed reports whether the record is a rejected
+// authentication attempt.
+func (r Record) AuthenticationFailed() bool {
+	if r.Action != ActionAuth {
+		return false
+	}
+
+	switch r.Status {
+	case AuthFailed, AuthFailedTooLong, AuthDirectoryError, AuthSyncerFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// AuthenticationSucceeded reports whether the record is a successful
+// authentication.
+func (r Record) AuthenticationSucceeded() bool {
+	return r.Action == ActionAuth && (r.Status == AuthOK || r.Status == AuthSyncerOK)
+}
+
+// ParseRecord decodes an audit record. Connection records nest their fields
+// under the action name, authentication records carry them at the top level
+// next to an action field:
+//
+//	{"ts":1655821384,"new_conn":{"id":2285001002,"srcip":"127.0.0.1",...}}
+//	{"ts":1655821384,"action":"auth","id":2285001002,"srcip":"127.0.0.1","status":8,...}
+func ParseRecord(data []byte) (Record, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Record{}, fmt.Errorf("baton-redis: invalid audit record: %w", err)
+	}
+
+	return recordFromFields(fields)
+}
+
+func recordFromFields(fields map[string]any) (Record, error) {
+	record := Record{Status: authStatusNotApplicable, Fields: fields}
+
+	body := fields
+	for _, action := range []string{ActionNewConn, ActionCloseConn} {
+		if nested, ok := fields[action].(map[string]any); ok {
+			record.Action = action
+			body = nested
+		}
+	}
+	if record.Action == "" {
+		record.Action = str(fields["action"])
+	}
+	if record.Action == "" {
+		return Record{}, fmt.Errorf("baton-redis: audit record without an action")
+	}
+
+	ts, err := strconv.ParseFloat(str(fields["ts"]), 64)
+	if err != nil {
+		return Record{}, fmt.Errorf("baton-redis: audit record without a valid ts: %w", err)
+	}
+	record.Time = time.Unix(int64(ts), 0).UTC()
+
+	record.ConnID = str(body["id"])
+	record.SourceIP = str(body["srcip"])
+	record.SourcePort = str(body["srcp"])
+	record.TargetIP = str(body["trgip"])
+	record.TargetPort = str(body["trgp"])
+	record.Hostname = str(body["hname"])
+	record.BdbName = str(body["bdb_name"])
+	record.BdbUID = str(body["bdb_uid"])
+	record.Username = str(body["username"])
+	record.Identity = str(body["identity"])
+
+	if record.Action == ActionAuth {
+		status, err := strconv.Atoi(str(body["status"]))
+		if err != nil {
+			return Record{}, fmt.Errorf("baton-redis: auth audit record without a valid status: %w", err)
+		}
+		record.Status = status
+	}
+
+	return record, nil
+}
+
+// str formats a decoded JSON value, numbers included, as a string.
+func str(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
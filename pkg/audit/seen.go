@@ -0,0 +1,42 @@
+package audit
+
+import "container/list"
+
+type userIP struct {
+	user string
+	ip   string
+}
+
+// seenIPs remembers the client IPs users authenticated from, forgetting the
+// least recently seen ones beyond its capacity.
+type seenIPs struct {
+	capacity int
+	order    *list.List // of userIP, most recently seen first
+	elements map[userIP]*list.Element
+}
+
+func newSeenIPs(capacity int) *seenIPs {
+	return &seenIPs{
+		capacity: capacity,
+		order:    list.New(),
+		elements: make(map[userIP]*list.Element),
+	}
+}
+
+// add records that user authenticated from ip and reports whether it is the
+// first time it is seen doing so.
+func (s *seenIPs) add(user, ip string) bool {
+	key := userIP{user: user, ip: ip}
+	if element, ok := s.elements[key]; ok {
+		s.order.MoveToFront(element)
+		return false
+	}
+
+	s.elements[key] = s.order.PushFront(key)
+	if s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.elements, oldest.Value.(userIP))
+	}
+	return true
+}
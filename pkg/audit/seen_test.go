@@ -0,0 +1,32 @@
+package audit
+
+import "testing"
+
+func TestSeenIPs(t *testing.T) {
+	seen := newSeenIPs(2)
+
+	steps := []struct {
+		user      string
+		ip        string
+		firstSeen bool
+	}{
+		{user: "alice", ip: "10.0.0.1", firstSeen: true},
+		{user: "alice", ip: "10.0.0.1", firstSeen: false},
+		{user: "bob", ip: "10.0.0.1", firstSeen: true},
+		// Seeing alice again keeps her IP over bob's.
+		{user: "alice", ip: "10.0.0.1", firstSeen: false},
+		{user: "alice", ip: "10.0.0.2", firstSeen: true},
+		{user: "alice", ip: "10.0.0.1", firstSeen: false},
+		// bob's IP was forgotten.
+		{user: "bob", ip: "10.0.0.1", firstSeen: true},
+	}
+
+	for i, step := range steps {
+		if firstSeen := seen.add(step.user, step.ip); firstSeen != step.firstSeen {
+			t.Errorf("Step %d: expected %s from %s first seen %t, got %t", i, step.user, step.ip, step.firstSeen, firstSeen)
+		}
+	}
+	if len(seen.elements) != 2 || seen.order.Len() != 2 {
+		t.Errorf("Expected 2 client IPs remembered, got %d", len(seen.elements))
+	}
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type cacheEntry struct {
+	done       chan struct{}
+	value      any
+	annotation annotations.Annotations
+	err        error
+}
+
+// responseCache keeps the objects read from the cluster for the duration of a
+// sync. Concurrent reads of the same key share a single request, and failed
+// requests aren't cached so the next read tries again.
+type responseCache struct {
+	mu         sync.Mutex
+	entries    map[string]*cacheEntry
+	generation uint64
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{entries: make(map[string]*cacheEntry)}
+}
+
+// Invalidate drops every cached object. Requests in flight complete, but their
+// results are only handed to the callers already waiting for them.
+func (c *responseCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*cacheEntry)
+	c.generation++
+}
+
+// peek returns the value cached for key, if it was fetched successfully.
+func (c *responseCache) peek(key string) (any, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	select {
+	case <-entry.done:
+		return entry.value, entry.err == nil
+	default:
+		return nil, false
+	}
+}
+
+// set caches value for key, as if it had been fetched.
+func (c *responseCache) set(key string, value any) {
+	entry := &cacheEntry{done: make(chan struct{}), value: value}
+	close(entry.done)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = entry
+}
+
+func (c *responseCache) get(
+	ctx context.Context,
+	key string,
+	fetch func(ctx context.Context) (any, annotations.Annotations, error),
+) (any, annotations.Annotations, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+
+		select {
+		case <-entry.done:
+			return entry.value, entry.annotation, entry.err
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	entry := &cacheEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	generation := c.generation
+	c.mu.Unlock()
+
+	entry.value, entry.annotation, entry.err = fetch(ctx)
+	close(entry.done)
+
+	if entry.err != nil {
+		c.mu.Lock()
+		if c.generation == generation && c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+
+	return entry.value, entry.annotation, entry.err
+}
+
+// cached returns the cached result for key, calling fetch when there is none.
+func cached[T any](
+	ctx context.Context,
+	c *responseCache,
+	key string,
+	fetch func(ctx context.Context) (T, annotations.Annotations, error),
+) (T, annotations.Annotations, error) {
+	value, annotation, err := c.get(ctx, key, func(ctx context.Context) (any, annotations.Annotations, error) {
+		return fetch(ctx)
+	})
+
+	var res T
+	if err != nil {
+		return res, nil, err
+	}
+	if value != nil {
+		res, _ = value.(T)
+	}
+
+	return res, annotation, nil
+}
+
+// InvalidateCache drops everything the client read from the cluster so far,
+// including the responses kept by the HTTP cache. It is called when a sync
+// starts and after every write.
+func (c *RedisClient) InvalidateCache(ctx context.Context) {
+	c.cache.Invalidate()
+
+	if err := uhttp.ClearCaches(ctx); err != nil {
+		ctxzap.Extract(ctx).Warn("baton-redis: unable to clear http cache", zap.Error(err))
+	}
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const getCertificates = "/v1/cluster/certificates"
+
+// certificateSuffix ends the names of the certificates returned by
+// /v1/cluster/certificates, such as proxy_cert, as opposed to their keys.
+const certificateSuffix = "_cert"
+
+// ListCertificates returns the PEM encoded certificates of the cluster, such as
+// the API, proxy, CM, syncer and metrics exporter ones, by name without the
+// _cert suffix. The private keys returned along with them are dropped, and the
+// response is kept out of the HTTP cache, which may be on disk. The result is
+// shared with every other caller until the cache is invalidated and must not
+// be modified.
+func (c *RedisClient) ListCertificates(ctx context.Context) (map[string]string, annotations.Annotations, error) {
+	return cached(ctx, c.cache, getCertificates, func(ctx context.Context) (map[string]string, annotations.Annotations, error) {
+		l := ctxzap.Extract(ctx)
+		var res map[string]interface{}
+
+		annotation, err := c.getResourcesFromAPI(withoutHTTPCache(ctx), getCertificates, &res)
+		if status.Code(err) == codes.NotFound {
+			return nil, nil, nil
+		}
+		if err != nil {
+			l.Error(fmt.Sprintf("Error getting resources: %s", err))
+			return nil, nil, err
+		}
+
+		certificates := make(map[string]string)
+		for key, value := range res {
+			name, ok := strings.CutSuffix(key, certificateSuffix)
+			if pem, isString := value.(string); ok && isString && pem != "" {
+				certificates[name] = pem
+			}
+		}
+
+		return certificates, annotation, nil
+	})
+}
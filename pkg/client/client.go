@@ -1,22 +1,43 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	encoding "encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/ratelimit"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	getUsers    = "/v1/users"
+	getUserById = "/v1/users/%v"
+	updateUser  = "/v1/users/%v"
 	getRoles    = "/v1/roles"
 	getRoleById = "/v1/roles/%v"
+	getNodes    = "/v1/nodes"
+	getCluster  = "/v1/cluster"
+	getLDAP     = "/v1/cluster/ldap"
+	getMappings = "/v1/ldap_mappings"
+	getBdbs     = "/v1/bdbs"
+	getBdbById  = "/v1/bdbs/%v"
+	getACLs     = "/v1/redis_acls"
+	redisACL    = "/v1/redis_acls/%v"
 )
 
 type RedisClient struct {
@@ -24,7 +45,39 @@ type RedisClient struct {
 	Password    string
 	ClusterHost string
 	APIPort     string
-	wrapper     *uhttp.BaseHttpClient
+	// NodeHosts are additional cluster nodes that are tried, in order, when
+	// ClusterHost doesn't respond.
+	NodeHosts []string
+	// DiscoverNodes adds the nodes reported by /v1/nodes to NodeHosts.
+	DiscoverNodes bool
+	// TLSConfig, when set, replaces the default TLS settings used to reach
+	// the cluster, e.g. to trust a private CA.
+	TLSConfig *tls.Config
+	// MaxRetries is the number of times a request failing with a transient
+	// error is retried, waiting between InitialBackoff and MaxBackoff.
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// IncrementalMaxAge enables incremental syncs, see StartSync. A sync is
+	// complete when the previous one is older than this.
+	IncrementalMaxAge time.Duration
+	// IncrementalStateFile, when set, keeps what the next sync needs to be
+	// incremental across runs of the connector.
+	IncrementalStateFile string
+	// FixtureDir, when set, records a sanitized copy of every request and
+	// response to the directory, see RecordingTransport.
+	FixtureDir string
+	wrapper    *uhttp.BaseHttpClient
+	cache      *responseCache
+
+	endpointsOnce  sync.Once
+	endpoints      *endpointPool
+	discoveryMutex sync.Mutex
+	discovered     bool
+
+	syncMutex  sync.Mutex
+	stateMutex sync.Mutex
+	state      *syncState
 }
 
 func New(ctx context.Context, redisClient *RedisClient) (*RedisClient, error) {
@@ -33,28 +86,63 @@ func New(ctx context.Context, redisClient *RedisClient) (*RedisClient, error) {
 		password    = redisClient.Password
 		clusterHost = redisClient.ClusterHost
 		apiPort     = redisClient.APIPort
+		nodeHosts   = redisClient.NodeHosts
+		discover    = redisClient.DiscoverNodes
+		maxRetries  = redisClient.MaxRetries
+		minBackoff  = redisClient.InitialBackoff
+		maxBackoff  = redisClient.MaxBackoff
+		fixtureDir  = redisClient.FixtureDir
+		maxAge      = redisClient.IncrementalMaxAge
+		stateFile   = redisClient.IncrementalStateFile
+		tlsConfig   = redisClient.TLSConfig
 	)
 
 	options := []uhttp.Option{
 		uhttp.WithLogger(true, ctxzap.Extract(ctx)),
 	}
+	if discover {
+		tlsConfig = withServerName(tlsConfig, clusterHost)
+	}
+	if tlsConfig != nil {
+		options = append(options, uhttp.WithTLSClientConfig(tlsConfig))
+	}
 
 	httpClient, err := uhttp.NewClient(ctx, options...)
 	if err != nil {
 		return nil, err
 	}
 
+	if fixtureDir != "" {
+		recorder, err := NewRecordingTransport(fixtureDir, httpClient.Transport)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Transport = recorder
+		ctxzap.Extract(ctx).Info("baton-redis: recording cluster responses", zap.String("dir", fixtureDir))
+	}
+
 	cli, err := uhttp.NewBaseHttpClientWithContext(context.Background(), httpClient)
 	if err != nil {
 		return nil, err
 	}
 
 	client := RedisClient{
-		wrapper:     cli,
-		Username:    username,
-		Password:    password,
-		ClusterHost: clusterHost,
-		APIPort:     apiPort,
+		wrapper:        cli,
+		cache:          newResponseCache(),
+		Username:       username,
+		Password:       password,
+		ClusterHost:    clusterHost,
+		APIPort:        apiPort,
+		NodeHosts:      nodeHosts,
+		DiscoverNodes:  discover,
+		TLSConfig:      tlsConfig,
+		MaxRetries:     maxRetries,
+		InitialBackoff: minBackoff,
+		MaxBackoff:     maxBackoff,
+		FixtureDir:     fixtureDir,
+
+		IncrementalMaxAge:    maxAge,
+		IncrementalStateFile: stateFile,
 	}
 
 	return &client, nil
@@ -67,6 +155,7 @@ func NewClient(username, password, clusterHost, apiPort string, httpClient ...*u
 	}
 	return &RedisClient{
 		wrapper:     wrapper,
+		cache:       newResponseCache(),
 		Username:    username,
 		Password:    password,
 		ClusterHost: clusterHost,
@@ -74,24 +163,130 @@ func NewClient(username, password, clusterHost, apiPort string, httpClient ...*u
 	}
 }
 
+// ListUsers returns the cluster users. The result is shared with every other
+// caller until the cache is invalidated and must not be modified.
 func (c *RedisClient) ListUsers(ctx context.Context) ([]User, annotations.Annotations, error) {
+	return cached(ctx, c.cache, getUsers, func(ctx context.Context) ([]User, annotations.Annotations, error) {
+		l := ctxzap.Extract(ctx)
+		var res []User
+
+		annotation, err := c.getResourcesFromAPI(ctx, getUsers, &res)
+		if err != nil {
+			l.Error(fmt.Sprintf("Error getting resources: %s", err))
+			return nil, nil, err
+		}
+		c.keep(ctx, getUsers, res)
+
+		return res, annotation, nil
+	})
+}
+
+// GetUser returns a single user as the cluster currently sees it, bypassing
+// the cache and the HTTP cache.
+func (c *RedisClient) GetUser(ctx context.Context, userUID int) (User, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
-	var res []User
+	var res User
 
-	annotation, err := c.getResourcesFromAPI(ctx, getUsers, &res)
+	annotation, err := c.getResourcesFromAPI(withoutHTTPCache(ctx), fmt.Sprintf(getUserById, userUID), &res)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
-		return nil, nil, err
+		return res, nil, err
+	}
+
+	return res, annotation, nil
+}
+
+// UpdateUser changes the given fields of a user and returns the updated user.
+func (c *RedisClient) UpdateUser(ctx context.Context, userUID int, fields map[string]any) (User, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	var res User
+
+	_, annotation, err := c.doRequestWithRetry(ctx, http.MethodPut, fmt.Sprintf(updateUser, userUID), fields, &res)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error updating user: %s", err))
+		return res, nil, err
 	}
 
 	return res, annotation, nil
 }
 
+// ListRoles returns the cluster roles. The result is shared with every other
+// caller until the cache is invalidated and must not be modified.
 func (c *RedisClient) ListRoles(ctx context.Context) ([]Role, annotations.Annotations, error) {
+	return cached(ctx, c.cache, getRoles, func(ctx context.Context) ([]Role, annotations.Annotations, error) {
+		l := ctxzap.Extract(ctx)
+		var res []Role
+
+		annotation, err := c.getResourcesFromAPI(ctx, getRoles, &res)
+		if err != nil {
+			l.Error(fmt.Sprintf("Error getting resources: %s", err))
+			return nil, nil, err
+		}
+		c.keep(ctx, getRoles, res)
+
+		return res, annotation, nil
+	})
+}
+
+// ListDatabases returns the cluster databases. The result is shared with every
+// other caller until the cache is invalidated and must not be modified.
+func (c *RedisClient) ListDatabases(ctx context.Context) ([]Database, annotations.Annotations, error) {
+	return cached(ctx, c.cache, getBdbs, func(ctx context.Context) ([]Database, annotations.Annotations, error) {
+		l := ctxzap.Extract(ctx)
+		var res []Database
+
+		annotation, err := c.getResourcesFromAPI(ctx, getBdbs, &res)
+		if err != nil {
+			l.Error(fmt.Sprintf("Error getting resources: %s", err))
+			return nil, nil, err
+		}
+		c.keep(ctx, getBdbs, res)
+
+		return res, annotation, nil
+	})
+}
+
+// FetchDatabases returns the cluster databases as the cluster currently sees
+// them, bypassing the cache and the HTTP cache, for the checks made before a
+// write.
+func (c *RedisClient) FetchDatabases(ctx context.Context) ([]Database, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	var res []Database
+
+	annotation, err := c.getResourcesFromAPI(withoutHTTPCache(ctx), getBdbs, &res)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, nil, err
+	}
+
+	return res, annotation, nil
+}
+
+// ListRedisACLs returns the cluster Redis ACLs. The result is shared with
+// every other caller until the cache is invalidated and must not be modified.
+func (c *RedisClient) ListRedisACLs(ctx context.Context) ([]RedisACL, annotations.Annotations, error) {
+	return cached(ctx, c.cache, getACLs, func(ctx context.Context) ([]RedisACL, annotations.Annotations, error) {
+		l := ctxzap.Extract(ctx)
+		var res []RedisACL
+
+		annotation, err := c.getResourcesFromAPI(ctx, getACLs, &res)
+		if err != nil {
+			l.Error(fmt.Sprintf("Error getting resources: %s", err))
+			return nil, nil, err
+		}
+
+		return res, annotation, nil
+	})
+}
+
+// FetchRedisACLs returns the cluster Redis ACLs as the cluster currently sees
+// them, bypassing the cache and the HTTP cache, for the checks made before a
+// write.
+func (c *RedisClient) FetchRedisACLs(ctx context.Context) ([]RedisACL, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
-	var res []Role
+	var res []RedisACL
 
-	annotation, err := c.getResourcesFromAPI(ctx, getRoles, &res)
+	annotation, err := c.getResourcesFromAPI(withoutHTTPCache(ctx), getACLs, &res)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
 		return nil, nil, err
@@ -100,6 +295,60 @@ func (c *RedisClient) ListRoles(ctx context.Context) ([]Role, annotations.Annota
 	return res, annotation, nil
 }
 
+// CreateRedisACL creates a Redis ACL named name with the rules of acl.
+func (c *RedisClient) CreateRedisACL(ctx context.Context, name, acl string) (RedisACL, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	var res RedisACL
+
+	_, annotation, err := c.doRequestWithRetry(ctx, http.MethodPost, getACLs, map[string]any{"name": name, "acl": acl}, &res)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error creating Redis ACL: %s", err))
+		return res, nil, err
+	}
+
+	return res, annotation, nil
+}
+
+// UpdateRedisACL replaces the rules of the Redis ACL with aclUID.
+func (c *RedisClient) UpdateRedisACL(ctx context.Context, aclUID int, acl string) (RedisACL, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	var res RedisACL
+
+	_, annotation, err := c.doRequestWithRetry(ctx, http.MethodPut, fmt.Sprintf(redisACL, aclUID), map[string]any{"acl": acl}, &res)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error updating Redis ACL: %s", err))
+		return res, nil, err
+	}
+
+	return res, annotation, nil
+}
+
+// DeleteRedisACL deletes the Redis ACL with aclUID.
+func (c *RedisClient) DeleteRedisACL(ctx context.Context, aclUID int) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	_, annotation, err := c.doRequestWithRetry(ctx, http.MethodDelete, fmt.Sprintf(redisACL, aclUID), nil, nil)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error deleting Redis ACL: %s", err))
+		return nil, err
+	}
+
+	return annotation, nil
+}
+
+func (c *RedisClient) GetDatabase(ctx context.Context, bdbUID int) (Database, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	var res Database
+
+	annotation, err := c.getResourcesFromAPI(ctx, fmt.Sprintf(getBdbById, bdbUID), &res)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return res, nil, err
+	}
+
+	return res, annotation, nil
+}
+
 func (c *RedisClient) GetRoleDetails(ctx context.Context, roleUID string) (Role, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	var res Role
@@ -113,32 +362,228 @@ func (c *RedisClient) GetRoleDetails(ctx context.Context, roleUID string) (Role,
 	return res, annotation, nil
 }
 
+func (c *RedisClient) GetCluster(ctx context.Context) (Cluster, annotations.Annotations, error) {
+	return cached(ctx, c.cache, getCluster, func(ctx context.Context) (Cluster, annotations.Annotations, error) {
+		l := ctxzap.Extract(ctx)
+		var res Cluster
+
+		annotation, err := c.getResourcesFromAPI(ctx, getCluster, &res)
+		if err != nil {
+			l.Error(fmt.Sprintf("Error getting resources: %s", err))
+			return res, nil, err
+		}
+
+		return res, annotation, nil
+	})
+}
+
+// GetLDAP returns the LDAP configuration of the cluster, empty on versions
+// without LDAP support.
+func (c *RedisClient) GetLDAP(ctx context.Context) (LDAP, annotations.Annotations, error) {
+	return cached(ctx, c.cache, getLDAP, func(ctx context.Context) (LDAP, annotations.Annotations, error) {
+		l := ctxzap.Extract(ctx)
+		var res LDAP
+
+		annotation, err := c.getResourcesFromAPI(ctx, getLDAP, &res)
+		if status.Code(err) == codes.NotFound {
+			return LDAP{}, nil, nil
+		}
+		if err != nil {
+			l.Error(fmt.Sprintf("Error getting resources: %s", err))
+			return res, nil, err
+		}
+
+		return res, annotation, nil
+	})
+}
+
+// ListLDAPMappings returns the LDAP mappings of the cluster, none on versions
+// without LDAP support. The result is shared with every other caller until the
+// cache is invalidated and must not be modified.
+func (c *RedisClient) ListLDAPMappings(ctx context.Context) ([]LDAPMapping, annotations.Annotations, error) {
+	return cached(ctx, c.cache, getMappings, func(ctx context.Context) ([]LDAPMapping, annotations.Annotations, error) {
+		l := ctxzap.Extract(ctx)
+		var res []LDAPMapping
+
+		annotation, err := c.getResourcesFromAPI(ctx, getMappings, &res)
+		if status.Code(err) == codes.NotFound {
+			return nil, nil, nil
+		}
+		if err != nil {
+			l.Error(fmt.Sprintf("Error getting resources: %s", err))
+			return nil, nil, err
+		}
+
+		return res, annotation, nil
+	})
+}
+
+func (c *RedisClient) ListNodes(ctx context.Context) ([]Node, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	var res []Node
+
+	annotation, err := c.getResourcesFromAPI(ctx, getNodes, &res)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, nil, err
+	}
+
+	return res, annotation, nil
+}
+
 func (c *RedisClient) getResourcesFromAPI(
 	ctx context.Context,
 	urlEndpoint string,
 	res any,
 ) (annotations.Annotations, error) {
-	l := ctxzap.Extract(ctx)
+	if c.DiscoverNodes && urlEndpoint != getNodes {
+		c.discoverNodes(ctx)
+	}
+
+	_, annotation, err := c.doRequestWithRetry(ctx, http.MethodGet, urlEndpoint, nil, &res)
 
-	urlAddress, err := url.Parse(c.ClusterHost + ":" + c.APIPort + urlEndpoint)
 	if err != nil {
-		l.Error(fmt.Sprintf("Error creating url: %s", err))
 		return nil, err
 	}
 
-	_, annotation, err := c.doRequest(ctx, http.MethodGet, urlAddress, &res)
+	return annotation, nil
+}
+
+// pool returns the nodes requests are sent to, built from ClusterHost followed
+// by NodeHosts.
+func (c *RedisClient) pool(ctx context.Context) *endpointPool {
+	l := ctxzap.Extract(ctx)
+
+	c.endpointsOnce.Do(func() {
+		c.endpoints = newEndpointPool()
+		for _, host := range append([]string{c.ClusterHost}, c.NodeHosts...) {
+			baseURL, err := c.nodeBaseURL(host)
+			if err != nil {
+				l.Error("baton-redis: ignoring invalid cluster node", zap.String("node", host), zap.Error(err))
+				continue
+			}
+			c.endpoints.Add(baseURL)
+		}
+	})
+
+	return c.endpoints
+}
+
+// nodeBaseURL turns a node address into the base URL of its REST API. Nodes
+// listed without a scheme use the scheme of ClusterHost, nodes listed without
+// a port use APIPort and nodes listed without a path use the path prefix of
+// ClusterHost, such as the one of a reverse proxy.
+func (c *RedisClient) nodeBaseURL(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if scheme, _, ok := strings.Cut(c.ClusterHost, "://"); ok && host != "" && !strings.Contains(host, "://") {
+		host = scheme + "://" + host
+	}
+
+	baseURL, err := NormalizeEndpoint(host, c.APIPort)
+	if err != nil {
+		return "", err
+	}
 
+	nodeURL, err := url.Parse(baseURL)
+	if err != nil || nodeURL.Path != "" {
+		return baseURL, err
+	}
+	clusterURL, err := NormalizeEndpoint(c.ClusterHost, c.APIPort)
 	if err != nil {
-		return nil, err
+		return baseURL, nil
+	}
+	parsed, err := url.Parse(clusterURL)
+	if err != nil {
+		return baseURL, nil
 	}
 
-	return annotation, nil
+	return baseURL + parsed.Path, nil
+}
+
+// discoverNodes adds the cluster nodes reported by the API to the pool. It is
+// done once per client; a failed discovery is retried on the next request and
+// never fails the request itself.
+func (c *RedisClient) discoverNodes(ctx context.Context) {
+	l := ctxzap.Extract(ctx)
+
+	c.discoveryMutex.Lock()
+	defer c.discoveryMutex.Unlock()
+
+	if c.discovered {
+		return
+	}
+
+	nodes, _, err := c.ListNodes(ctx)
+	if err != nil {
+		l.Warn("baton-redis: unable to discover cluster nodes", zap.Error(err))
+		return
+	}
+
+	for _, node := range nodes {
+		baseURL, err := c.nodeBaseURL(node.Address())
+		if err != nil {
+			l.Warn("baton-redis: ignoring discovered cluster node", zap.String("node", node.Address()), zap.Error(err))
+			continue
+		}
+		c.pool(ctx).Add(baseURL)
+	}
+	c.discovered = true
+}
+
+// doRequestWithFailover sends the request to the node that answered last and
+// moves on to the next node whenever a node is unreachable or unavailable.
+// Requests that aren't idempotent only move on when they never reached the
+// node, a node failing after receiving one may have processed it.
+func (c *RedisClient) doRequestWithFailover(
+	ctx context.Context,
+	method string,
+	urlEndpoint string,
+	body interface{},
+	res interface{},
+) (http.Header, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	nodes := c.pool(ctx).Candidates()
+	if len(nodes) == 0 {
+		return nil, nil, fmt.Errorf("baton-redis: no valid cluster endpoint configured")
+	}
+
+	var (
+		lastHeader http.Header
+		lastErr    error
+	)
+	for _, node := range nodes {
+		urlAddress, err := url.Parse(node.baseURL + urlEndpoint)
+		if err != nil {
+			l.Error(fmt.Sprintf("Error creating url: %s", err))
+			return nil, nil, err
+		}
+
+		header, annotation, err := c.doRequest(ctx, method, urlAddress, body, res)
+		if !isNodeFailure(err) || (!isIdempotent(method) && !isConnectError(err)) {
+			if err == nil {
+				c.pool(ctx).MarkSuccess(node)
+			}
+			return header, annotation, err
+		}
+
+		l.Warn(
+			"baton-redis: cluster node unavailable, trying next node",
+			zap.String("node", node.baseURL),
+			zap.Error(err),
+		)
+		c.pool(ctx).MarkFailure(node)
+		lastHeader, lastErr = header, err
+	}
+
+	return lastHeader, nil, lastErr
 }
 
 func (c *RedisClient) doRequest(
 	ctx context.Context,
 	method string,
 	urlAddress *url.URL,
+	body interface{},
 	res interface{},
 ) (http.Header, annotations.Annotations, error) {
 	var (
@@ -148,14 +593,16 @@ func (c *RedisClient) doRequest(
 
 	authorizationToken := encoding.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", c.Username, c.Password)))
 
-	req, err := c.wrapper.NewRequest(
-		ctx,
-		method,
-		urlAddress,
+	requestOptions := []uhttp.RequestOption{
 		uhttp.WithContentTypeJSONHeader(),
 		uhttp.WithAcceptJSONHeader(),
 		uhttp.WithHeader("Authorization", "Basic "+authorizationToken),
-	)
+	}
+	if body != nil {
+		requestOptions = append(requestOptions, uhttp.WithJSONBody(body))
+	}
+
+	req, err := c.wrapper.NewRequest(ctx, method, urlAddress, requestOptions...)
 
 	if err != nil {
 		return nil, nil, err
@@ -163,6 +610,10 @@ func (c *RedisClient) doRequest(
 
 	switch method {
 	case http.MethodGet, http.MethodPut, http.MethodPost:
+		if method == http.MethodGet && skipsHTTPCache(ctx) {
+			resp, err = c.doUncached(req, res)
+			break
+		}
 		var doOptions []uhttp.DoOption
 		if res != nil {
 			doOptions = append(doOptions, uhttp.WithResponse(&res))
@@ -179,6 +630,14 @@ func (c *RedisClient) doRequest(
 	}
 
 	if err != nil {
+		// The headers of a failed response are kept so callers can honor
+		// Retry-After.
+		if resp != nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				err = errors.Join(errTooManyRequests, err)
+			}
+			return resp.Header, nil, err
+		}
 		return nil, nil, err
 	}
 
@@ -195,3 +654,71 @@ func (c *RedisClient) doRequest(
 
 	return nil, nil, err
 }
+
+type uncachedKey struct{}
+
+// withoutHTTPCache returns a context whose GET requests are neither answered
+// from nor stored in the HTTP cache, for the reads that must see the current
+// state of the cluster or must not be kept, possibly on disk.
+func withoutHTTPCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, uncachedKey{}, true)
+}
+
+func skipsHTTPCache(ctx context.Context) bool {
+	uncached, _ := ctx.Value(uncachedKey{}).(bool)
+	return uncached
+}
+
+// doUncached sends req like the wrapper does, mapping the status of failed
+// responses to the same errors, without going through the HTTP cache the
+// wrapper always uses for GET requests.
+func (c *RedisClient) doUncached(req *http.Request, res interface{}) (*http.Response, error) {
+	resp, err := c.wrapper.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, uhttp.WrapErrors(codes.Unavailable, "unable to read response", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, uhttp.WrapErrorsWithRateLimitInfo(statusCode(resp.StatusCode), resp)
+	}
+
+	if res != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, res); err != nil {
+			return resp, fmt.Errorf("baton-redis: failed to unmarshal json response: %w", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// statusCode returns the code the wrapper gives to a failed HTTP status.
+func statusCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	}
+	if httpStatus >= 500 && httpStatus <= 599 {
+		return codes.Unavailable
+	}
+
+	return codes.Unknown
+}
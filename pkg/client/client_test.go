@@ -1,7 +1,23 @@
 package client
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestRedisClient_AddCredentials(t *testing.T) {
@@ -17,3 +33,473 @@ func TestRedisClient_AddCredentials(t *testing.T) {
 		t.Errorf("Set password failed. Expected %s, got %s", mockPassword, client.Password)
 	}
 }
+
+// newNodeServer starts a fake cluster node that answers every request with
+// statusCode and counts the requests it received.
+func newNodeServer(t *testing.T, statusCode int, body string, hits *int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRedisClient_FailsOverToNextNode(t *testing.T) {
+	var downHits, upHits int32
+	down := newNodeServer(t, http.StatusServiceUnavailable, `{}`, &downHits)
+	up := newNodeServer(t, http.StatusOK, `[{"uid": 1, "name": "admin"}]`, &upHits)
+
+	client := NewClient("username", "password", down.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.NodeHosts = []string{up.URL}
+
+	ctx := context.Background()
+	if _, _, err := client.ListUsers(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, _, err := client.ListRoles(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, _, err := client.ListNodes(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The unavailable node is only tried once, after that the client sticks
+	// to the node that answered.
+	if downHits != 1 {
+		t.Errorf("Expected 1 request to the unavailable node, got %d", downHits)
+	}
+	if upHits != 3 {
+		t.Errorf("Expected 3 requests to the available node, got %d", upHits)
+	}
+}
+
+func TestRedisClient_DoesNotFailOverOnClientErrors(t *testing.T) {
+	var forbiddenHits, upHits int32
+	forbidden := newNodeServer(t, http.StatusForbidden, `{}`, &forbiddenHits)
+	up := newNodeServer(t, http.StatusOK, `[]`, &upHits)
+
+	client := NewClient("username", "password", forbidden.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.NodeHosts = []string{up.URL}
+
+	if _, _, err := client.ListUsers(context.Background()); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if upHits != 0 {
+		t.Errorf("Expected no request to the second node, got %d", upHits)
+	}
+}
+
+func TestRedisClient_DoesNotReplayWritesOnAnotherNode(t *testing.T) {
+	var downHits, upHits int32
+	down := newNodeServer(t, http.StatusServiceUnavailable, `{}`, &downHits)
+	up := newNodeServer(t, http.StatusOK, `{"uid": 3, "name": "Reports"}`, &upHits)
+
+	client := NewClient("username", "password", down.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.NodeHosts = []string{up.URL}
+	client.MaxRetries = 2
+
+	if _, _, err := client.CreateRedisACL(context.Background(), "Reports", "+@read ~*"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if downHits != 1 || upHits != 0 {
+		t.Errorf("Expected the create to be sent once, got %d and %d requests", downHits, upHits)
+	}
+}
+
+func TestRedisClient_BacksOffOnTooManyRequests(t *testing.T) {
+	var hits, otherHits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"uid": 3, "name": "Reports"}`))
+	}))
+	t.Cleanup(server.Close)
+	other := newNodeServer(t, http.StatusOK, `{}`, &otherHits)
+
+	client := NewClient("username", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.NodeHosts = []string{other.URL}
+	client.MaxRetries = 1
+
+	if _, _, err := client.CreateRedisACL(context.Background(), "Reports", "+@read ~*"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if hits != 2 || otherHits != 0 {
+		t.Errorf("Expected the throttled node to be retried, got %d and %d requests", hits, otherHits)
+	}
+}
+
+func TestRedisClient_DiscoversNodes(t *testing.T) {
+	var upHits int32
+	up := newNodeServer(t, http.StatusOK, `[]`, &upHits)
+
+	var first *httptest.Server
+	first = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == getNodes {
+			_, _ = w.Write([]byte(`[{"uid": 1, "addr": "` + first.URL + `"}, {"uid": 2, "addr": "` + up.URL + `"}]`))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(first.Close)
+
+	client := NewClient("username", "password", first.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.DiscoverNodes = true
+
+	if _, _, err := client.ListUsers(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if upHits != 1 {
+		t.Errorf("Expected the discovered node to answer, got %d requests", upHits)
+	}
+}
+
+func TestRedisClient_RetriesTransientErrors(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"uid": 1, "name": "admin"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("username", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.MaxRetries = 3
+
+	users, _, err := client.ListUsers(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(users) != 1 {
+		t.Errorf("Expected 1 user, got %d", len(users))
+	}
+	if hits != 3 {
+		t.Errorf("Expected 3 requests, got %d", hits)
+	}
+}
+
+func TestRedisClient_GivesUpAfterMaxRetries(t *testing.T) {
+	var hits int32
+	server := newNodeServer(t, http.StatusServiceUnavailable, `{}`, &hits)
+
+	client := NewClient("username", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.MaxRetries = 2
+	client.InitialBackoff = time.Millisecond
+	client.MaxBackoff = time.Millisecond
+
+	if _, _, err := client.ListUsers(context.Background()); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if hits != 3 {
+		t.Errorf("Expected 3 requests, got %d", hits)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	unavailable := uhttp.WrapErrors(codes.Unavailable, "service unavailable")
+	refused := &url.Error{Op: "Post", URL: "https://cluster", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	reset := &url.Error{Op: "Post", URL: "https://cluster", Err: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}}
+
+	testCases := []struct {
+		method   string
+		err      error
+		expected bool
+	}{
+		{method: http.MethodGet, err: unavailable, expected: true},
+		{method: http.MethodPut, err: reset, expected: true},
+		{method: http.MethodDelete, err: unavailable, expected: true},
+		{method: http.MethodGet, err: uhttp.WrapErrors(codes.NotFound, "not found"), expected: false},
+		{method: http.MethodPost, err: unavailable, expected: false},
+		{method: http.MethodPost, err: reset, expected: false},
+		{method: http.MethodPost, err: refused, expected: true},
+		{method: http.MethodPost, err: errors.Join(errTooManyRequests, unavailable), expected: true},
+	}
+
+	for _, tc := range testCases {
+		if got := isRetryable(tc.method, tc.err); got != tc.expected {
+			t.Errorf("isRetryable(%s, %v) = %v, expected %v", tc.method, tc.err, got, tc.expected)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 2, 25, 13, 46, 12, 0, time.UTC)
+
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{value: "", ok: false},
+		{value: "5", expected: 5 * time.Second, ok: true},
+		{value: now.Add(10 * time.Second).Format(http.TimeFormat), expected: 10 * time.Second, ok: true},
+		{value: now.Add(-time.Minute).Format(http.TimeFormat), expected: 0, ok: true},
+		{value: "soon", ok: false},
+		{value: "99999999999999999", expected: math.MaxInt64, ok: true},
+	}
+
+	for _, tc := range testCases {
+		header := http.Header{}
+		if tc.value != "" {
+			header.Set("Retry-After", tc.value)
+		}
+
+		got, ok := parseRetryAfter(header, now)
+		if ok != tc.ok || got != tc.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, expected %v, %v", tc.value, got, ok, tc.expected, tc.ok)
+		}
+	}
+}
+
+func TestRedisClient_Backoff(t *testing.T) {
+	client := NewClient("username", "password", "", "")
+	client.InitialBackoff = 100 * time.Millisecond
+	client.MaxBackoff = time.Second
+
+	for attempt, expected := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		expected *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if got := client.backoff(attempt, nil); got < expected/2 || got > expected {
+				t.Fatalf("Expected backoff for attempt %d between %v and %v, got %v", attempt, expected/2, expected, got)
+			}
+		}
+	}
+
+	for _, value := range []string{"2", "3600", "99999999999999999", time.Now().Add(time.Hour).Format(http.TimeFormat)} {
+		header := http.Header{"Retry-After": []string{value}}
+		if got := client.backoff(0, header); got != client.MaxBackoff {
+			t.Errorf("Expected Retry-After %q to be capped at %v, got %v", value, client.MaxBackoff, got)
+		}
+	}
+	if got := client.backoff(0, http.Header{"Retry-After": []string{"0"}}); got != 0 {
+		t.Errorf("Expected Retry-After 0 to be honored, got %v", got)
+	}
+}
+
+func TestRedisClient_CachesConcurrentReads(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"uid": 1, "name": "admin"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("username", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			users, _, err := client.ListUsers(ctx)
+			if err == nil && len(users) != 1 {
+				err = errors.New("unexpected users")
+			}
+			errs <- err
+		}()
+	}
+
+	// Give every goroutine the chance to wait on the request in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("Expected concurrent reads to share 1 request, got %d", hits)
+	}
+
+	client.InvalidateCache(ctx)
+	if _, _, err := client.ListUsers(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if hits != 2 {
+		t.Errorf("Expected a new request after invalidation, got %d requests", hits)
+	}
+}
+
+func TestRedisClient_DoesNotCacheErrors(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("username", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	ctx := context.Background()
+
+	if _, _, err := client.ListRoles(ctx); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, _, err := client.ListRoles(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if hits != 2 {
+		t.Errorf("Expected 2 requests, got %d", hits)
+	}
+}
+
+func TestRedisClient_FailsOverBetweenClusterNodes(t *testing.T) {
+	cluster := fakeserver.NewWithDefaults("admin@example.com", "password")
+	node1, node2, node3 := cluster.Start(), cluster.Start(), cluster.Start()
+	defer node2.Close()
+	defer node3.Close()
+
+	client := NewClient("admin@example.com", "password", node1.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.NodeHosts = []string{node2.URL, node3.URL}
+	ctx := context.Background()
+
+	if _, _, err := client.ListUsers(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Take the node that answered down, like a rolling upgrade does.
+	node1.Close()
+	client.InvalidateCache(ctx)
+
+	users, _, err := client.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("Expected 2 users, got %d", len(users))
+	}
+}
+
+func TestRedisClient_DiscoveredNodesKeepPathPrefix(t *testing.T) {
+	var paths []string
+	var mu sync.Mutex
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(up.Close)
+
+	var first *httptest.Server
+	first = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/redis"+getNodes {
+			_, _ = w.Write([]byte(`[{"uid": 1, "addr": "` + first.Listener.Addr().String() + `"}, {"uid": 2, "addr": "` + up.Listener.Addr().String() + `"}]`))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(first.Close)
+
+	client := NewClient("username", "password", first.URL+"/redis", "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.DiscoverNodes = true
+
+	if _, _, err := client.ListUsers(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "/redis"+getUsers {
+		t.Errorf("Expected the discovered node to be sent /redis%s, got %v", getUsers, paths)
+	}
+}
+
+func TestWithServerName(t *testing.T) {
+	if config := withServerName(nil, "https://cluster.example.com:9443/redis"); config == nil || config.ServerName != "cluster.example.com" {
+		t.Errorf("Expected the cluster host name to be verified, got %v", config)
+	}
+	if config := withServerName(nil, "10.0.0.1"); config != nil {
+		t.Errorf("Expected an address cluster host to keep the default settings, got %v", config)
+	}
+
+	configured := &tls.Config{ServerName: "cluster.internal", MinVersion: tls.VersionTLS12}
+	if config := withServerName(configured, "cluster.example.com"); config.ServerName != "cluster.internal" {
+		t.Errorf("Expected the configured server name to be kept, got %s", config.ServerName)
+	}
+	withCA := &tls.Config{MinVersion: tls.VersionTLS13}
+	if config := withServerName(withCA, "cluster.example.com"); config.ServerName != "cluster.example.com" || config.MinVersion != tls.VersionTLS13 || withCA.ServerName != "" {
+		t.Errorf("Expected a copy of the configured settings with the server name, got %v", config)
+	}
+}
+
+func TestRedisClient_GetUserBypassesHTTPCache(t *testing.T) {
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	uid := fake.MustAdd(fakeserver.Users, fakeserver.Object{"name": "Bob", "email": "bob@example.com"})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	client := NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	ctx := context.Background()
+
+	if _, _, err := client.GetUser(ctx, uid); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := fake.Update(fakeserver.Users, uid, fakeserver.Object{"name": "Robert"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	user, _, err := client.GetUser(ctx, uid)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if user.Name != "Robert" {
+		t.Errorf("Expected the current name Robert, got %s", user.Name)
+	}
+
+	if _, _, err := client.GetUser(ctx, 999); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestRedisClient_ListCertificatesBypassesHTTPCache(t *testing.T) {
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.SetCertificates(fakeserver.Object{"api_cert": "old", "api_key": "secret"})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	client := NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	ctx := context.Background()
+
+	if _, _, err := client.ListCertificates(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	fake.SetCertificates(fakeserver.Object{"api_cert": "new"})
+	// Only the response cache is dropped, the HTTP cache would still answer.
+	client.cache.Invalidate()
+
+	certificates, _, err := client.ListCertificates(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if certificates["api"] != "new" {
+		t.Errorf("Expected the current certificate, got %v", certificates)
+	}
+	if _, ok := certificates["api_key"]; ok {
+		t.Errorf("Expected the private key to be dropped, got %v", certificates)
+	}
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const getCrdbs = "/v1/crdbs"
+
+// CRDBCluster is a cluster taking part in an Active-Active database.
+type CRDBCluster struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// CRDBInstance is the database of an Active-Active database on one of its
+// clusters. DBUID is the uid of the database on that cluster.
+type CRDBInstance struct {
+	ID      int         `json:"id"`
+	Cluster CRDBCluster `json:"cluster"`
+	DBUID   ObjectUID   `json:"db_uid"`
+	Extra   Extra       `json:"-"`
+}
+
+func (i *CRDBInstance) UnmarshalJSON(data []byte) error {
+	type crdbInstance CRDBInstance
+	var res crdbInstance
+	extra, err := unmarshalWithExtra(data, &res)
+	if err != nil {
+		return err
+	}
+	*i = CRDBInstance(res)
+	i.Extra = extra
+	return nil
+}
+
+func (i CRDBInstance) MarshalJSON() ([]byte, error) {
+	type crdbInstance CRDBInstance
+	return marshalWithExtra(crdbInstance(i), i.Extra)
+}
+
+// CRDBLocalDatabase tells which instance of an Active-Active database is
+// hosted by the cluster answering the request.
+type CRDBLocalDatabase struct {
+	ID     int       `json:"id"`
+	BdbUID ObjectUID `json:"bdb_uid"`
+}
+
+// CRDB is an Active-Active database, see
+// https://redis.io/docs/latest/operate/rs/references/rest-api/objects/crdb/.
+type CRDB struct {
+	GUID           string              `json:"guid"`
+	Name           string              `json:"name"`
+	Encryption     bool                `json:"encryption"`
+	Instances      []CRDBInstance      `json:"instances"`
+	LocalDatabases []CRDBLocalDatabase `json:"local_databases,omitempty"`
+	Extra          Extra               `json:"-"`
+}
+
+func (c *CRDB) UnmarshalJSON(data []byte) error {
+	type crdb CRDB
+	var res crdb
+	extra, err := unmarshalWithExtra(data, &res)
+	if err != nil {
+		return err
+	}
+	*c = CRDB(res)
+	c.Extra = extra
+	return nil
+}
+
+func (c CRDB) MarshalJSON() ([]byte, error) {
+	type crdb CRDB
+	return marshalWithExtra(crdb(c), c.Extra)
+}
+
+// LocalDatabaseUID returns the uid of the database instance on the cluster
+// answering the request, or "" when the instance is hosted elsewhere.
+func (c CRDB) LocalDatabaseUID(instanceID int) ObjectUID {
+	for _, local := range c.LocalDatabases {
+		if local.ID == instanceID {
+			return local.BdbUID
+		}
+	}
+	return ""
+}
+
+// ListCRDBs returns the Active-Active databases the cluster takes part in.
+// Clusters without Active-Active support have none. The result is shared with
+// every other caller until the cache is invalidated and must not be modified.
+func (c *RedisClient) ListCRDBs(ctx context.Context) ([]CRDB, annotations.Annotations, error) {
+	return cached(ctx, c.cache, getCrdbs, func(ctx context.Context) ([]CRDB, annotations.Annotations, error) {
+		l := ctxzap.Extract(ctx)
+		var res []CRDB
+
+		annotation, err := c.getResourcesFromAPI(ctx, getCrdbs, &res)
+		if status.Code(err) == codes.NotFound {
+			return nil, nil, nil
+		}
+		if err != nil {
+			l.Error(fmt.Sprintf("Error getting resources: %s", err))
+			return nil, nil, err
+		}
+
+		return res, annotation, nil
+	})
+}
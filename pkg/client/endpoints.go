@@ -0,0 +1,271 @@
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// endpointCooldown is how long a node that failed to answer is skipped before
+// it is tried again ahead of the nodes that are still considered healthy.
+const endpointCooldown = 30 * time.Second
+
+// NormalizeEndpoint validates a cluster address and returns the base URL of its
+// REST API. The address may carry a scheme (https is assumed when it doesn't),
+// a port (port is used when it doesn't), an IPv6 literal with or without
+// brackets, and a path prefix for clusters served behind a reverse proxy.
+func NormalizeEndpoint(host, port string) (string, error) {
+	raw := strings.TrimSpace(host)
+	if raw == "" {
+		return "", fmt.Errorf("cluster host is empty")
+	}
+
+	scheme := "https"
+	if i := strings.Index(raw, "://"); i >= 0 {
+		scheme = strings.ToLower(raw[:i])
+		raw = raw[i+len("://"):]
+	}
+	if scheme != "https" && scheme != "http" {
+		return "", fmt.Errorf("cluster host %q: unsupported scheme %q, expected http or https", host, scheme)
+	}
+	if strings.ContainsAny(raw, "?#@") {
+		return "", fmt.Errorf("cluster host %q: must not contain credentials, a query or a fragment", host)
+	}
+
+	hostPort, path, _ := strings.Cut(raw, "/")
+	path = strings.Trim(path, "/")
+
+	hostname, hostPortNumber, err := splitHostPort(hostPort)
+	if err != nil {
+		return "", fmt.Errorf("cluster host %q: %w", host, err)
+	}
+	if hostname == "" {
+		return "", fmt.Errorf("cluster host %q: missing host name", host)
+	}
+
+	if hostPortNumber == "" {
+		hostPortNumber = strings.TrimSpace(port)
+	}
+	if hostPortNumber == "" {
+		return "", fmt.Errorf("cluster host %q: missing API port", host)
+	}
+	if err := validatePort(hostPortNumber); err != nil {
+		return "", fmt.Errorf("cluster host %q: %w", host, err)
+	}
+
+	u := url.URL{
+		Scheme: scheme,
+		Host:   net.JoinHostPort(hostname, hostPortNumber),
+	}
+	if path != "" {
+		u.Path = "/" + path
+	}
+
+	return u.String(), nil
+}
+
+// splitHostPort splits an address that may or may not carry a port. Unlike
+// net.SplitHostPort it accepts IPv6 literals without brackets.
+func splitHostPort(hostPort string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(hostPort, "["):
+		end := strings.Index(hostPort, "]")
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated IPv6 address")
+		}
+		hostname, rest := hostPort[1:end], hostPort[end+1:]
+		if net.ParseIP(hostname) == nil {
+			return "", "", fmt.Errorf("invalid IPv6 address %q", hostname)
+		}
+		if rest == "" {
+			return hostname, "", nil
+		}
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", fmt.Errorf("unexpected %q after IPv6 address", rest)
+		}
+		return hostname, rest[1:], nil
+	case strings.Count(hostPort, ":") > 1:
+		// A bare IPv6 literal, a port can only be given with brackets.
+		if net.ParseIP(hostPort) == nil {
+			return "", "", fmt.Errorf("invalid IPv6 address %q, use brackets to add a port", hostPort)
+		}
+		return hostPort, "", nil
+	case strings.Contains(hostPort, ":"):
+		hostname, port, _ := strings.Cut(hostPort, ":")
+		return hostname, port, nil
+	default:
+		return hostPort, "", nil
+	}
+}
+
+func validatePort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("port %q is not a number", port)
+	}
+	if n < 1 || n > 65535 {
+		return fmt.Errorf("port %d is out of range 1-65535", n)
+	}
+	return nil
+}
+
+// withServerName returns config, or the default TLS settings when nil,
+// verifying the certificate of every node against the host name of
+// clusterHost: discovered nodes are dialled by address, while the cluster
+// certificate is issued for its FQDN. A server name set in config, or a
+// clusterHost that is an address itself, is left alone.
+func withServerName(config *tls.Config, clusterHost string) *tls.Config {
+	if config != nil && config.ServerName != "" {
+		return config
+	}
+
+	baseURL, err := NormalizeEndpoint(clusterHost, "443")
+	if err != nil {
+		return config
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil || net.ParseIP(parsed.Hostname()) != nil {
+		return config
+	}
+
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	} else {
+		config = config.Clone()
+	}
+	config.ServerName = parsed.Hostname()
+
+	return config
+}
+
+type endpoint struct {
+	baseURL   string
+	failures  int
+	downUntil time.Time
+}
+
+// endpointPool tracks the cluster nodes the client can talk to. Every node of
+// a Redis Enterprise cluster serves the REST API, so any of them can answer a
+// request. The pool sticks to the node that answered last and only moves on
+// when that node stops responding.
+type endpointPool struct {
+	mu        sync.Mutex
+	endpoints []*endpoint
+	current   int
+	now       func() time.Time
+}
+
+func newEndpointPool(baseURLs ...string) *endpointPool {
+	p := &endpointPool{now: time.Now}
+	for _, baseURL := range baseURLs {
+		p.add(baseURL)
+	}
+	return p
+}
+
+// add appends a node to the end of the pool unless it is already known.
+func (p *endpointPool) add(baseURL string) {
+	if baseURL == "" {
+		return
+	}
+
+	for _, e := range p.endpoints {
+		if e.baseURL == baseURL {
+			return
+		}
+	}
+
+	p.endpoints = append(p.endpoints, &endpoint{baseURL: baseURL})
+}
+
+// Add registers additional nodes, e.g. the ones discovered from /v1/nodes.
+func (p *endpointPool) Add(baseURLs ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, baseURL := range baseURLs {
+		p.add(baseURL)
+	}
+}
+
+// Candidates returns the nodes in the order they should be tried: the node
+// that answered last, then the remaining healthy nodes in configuration order,
+// then the nodes that are still cooling down after a failure.
+func (p *endpointPool) Candidates() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	healthy := make([]*endpoint, 0, len(p.endpoints))
+	var down []*endpoint
+
+	for i := range p.endpoints {
+		e := p.endpoints[(p.current+i)%len(p.endpoints)]
+		if now.Before(e.downUntil) {
+			down = append(down, e)
+			continue
+		}
+		healthy = append(healthy, e)
+	}
+
+	return append(healthy, down...)
+}
+
+// MarkSuccess makes e the node subsequent requests are sent to first.
+func (p *endpointPool) MarkSuccess(e *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.failures = 0
+	e.downUntil = time.Time{}
+	for i, candidate := range p.endpoints {
+		if candidate == e {
+			p.current = i
+			return
+		}
+	}
+}
+
+// MarkFailure takes e out of rotation for endpointCooldown.
+func (p *endpointPool) MarkFailure(e *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.failures++
+	e.downUntil = p.now().Add(endpointCooldown)
+}
+
+// isNodeFailure reports whether err means the node itself could not serve the
+// request (unreachable, timing out or answering with a 5xx while it is being
+// upgraded), as opposed to the cluster rejecting or throttling the request.
+func isNodeFailure(err error) bool {
+	if err == nil || errors.Is(err, errTooManyRequests) {
+		return false
+	}
+
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return true
+		default:
+			return false
+		}
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Extra holds the fields of an API object the model doesn't declare, so fields
+// added by newer cluster versions are kept and written back unchanged.
+type Extra map[string]json.RawMessage
+
+var knownFields sync.Map // map[reflect.Type]map[string]bool
+
+// jsonFields returns the JSON names of the fields declared by a struct type.
+func jsonFields(t reflect.Type) map[string]bool {
+	if fields, ok := knownFields.Load(t); ok {
+		return fields.(map[string]bool)
+	}
+
+	fields := make(map[string]bool, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		fields[name] = true
+	}
+
+	knownFields.Store(t, fields)
+	return fields
+}
+
+// unmarshalWithExtra decodes data into v, a pointer to a struct without custom
+// JSON methods, and returns the fields v doesn't declare.
+func unmarshalWithExtra(data []byte, v any) (Extra, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	known := jsonFields(reflect.TypeOf(v).Elem())
+	var extra Extra
+	for name, value := range raw {
+		if known[name] {
+			continue
+		}
+		if extra == nil {
+			extra = make(Extra)
+		}
+		extra[name] = value
+	}
+
+	return extra, nil
+}
+
+// marshalWithExtra encodes v, a struct without custom JSON methods, merged with
+// extra. Declared fields win over extra ones of the same name.
+func marshalWithExtra(v any, extra Extra) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// UIDList is a list of object uids, which cluster versions return either as
+// numbers or as strings.
+type UIDList []string
+
+func (l *UIDList) UnmarshalJSON(data []byte) error {
+	var values []any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	res := make(UIDList, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			res = append(res, v)
+		case float64:
+			res = append(res, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+	*l = res
+
+	return nil
+}
+
+// ObjectUID is the uid of an object referenced by another one, which cluster
+// versions return either as a number or as a string.
+type ObjectUID string
+
+func (u *ObjectUID) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case string:
+		*u = ObjectUID(v)
+	case float64:
+		*u = ObjectUID(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		*u = ""
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redacted replaces every scrubbed value in a recorded fixture.
+const redacted = "REDACTED"
+
+// scrubbedHeaders are the headers that carry credentials, or change on every
+// request, and are never written to a fixture.
+var scrubbedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "Date", "Content-Length"}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FixtureRequest is the sanitized request of a recorded exchange.
+type FixtureRequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Query  string          `json:"query,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// FixtureResponse is the sanitized response of a recorded exchange. Body holds
+// JSON responses, Text holds everything else.
+type FixtureResponse struct {
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   json.RawMessage   `json:"body,omitempty"`
+	Text   string            `json:"text,omitempty"`
+}
+
+// Fixture is a request/response pair captured from a cluster.
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureName returns the file name a request is recorded under, without the
+// directory.
+func FixtureName(method, path, query string) string {
+	name := method + "_" + strings.Trim(path, "/")
+	if query != "" {
+		name += "_" + query
+	}
+	return unsafeFileChars.ReplaceAllString(name, "_") + ".json"
+}
+
+// RecordingTransport passes requests on to Next and writes a sanitized copy of
+// every exchange to Dir, one file per distinct request. Passwords, tokens and
+// other secrets are scrubbed from headers and JSON bodies before anything is
+// written, so fixtures recorded from a real cluster can be committed.
+type RecordingTransport struct {
+	Dir  string
+	Next http.RoundTripper
+
+	mu sync.Mutex
+}
+
+// NewRecordingTransport returns a transport recording into dir, creating it
+// when it doesn't exist.
+func NewRecordingTransport(dir string, next http.RoundTripper) (*RecordingTransport, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("baton-redis: unable to create fixture directory: %w", err)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &RecordingTransport{Dir: dir, Next: next}, nil
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fixture := Fixture{
+		Request: FixtureRequest{
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Query:  req.URL.RawQuery,
+		},
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		if json.Valid(body) {
+			fixture.Request.Body = ScrubJSON(body)
+		}
+	}
+
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	fixture.Response.Status = resp.StatusCode
+	fixture.Response.Header = scrubHeader(resp.Header)
+	if json.Valid(body) {
+		fixture.Response.Body = ScrubJSON(body)
+	} else {
+		fixture.Response.Text = string(body)
+	}
+
+	if err := t.write(fixture); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (t *RecordingTransport) write(fixture Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	name := FixtureName(fixture.Request.Method, fixture.Request.Path, fixture.Request.Query)
+	if err := os.WriteFile(filepath.Join(t.Dir, name), append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("baton-redis: unable to write fixture %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func scrubHeader(header http.Header) map[string]string {
+	res := make(map[string]string, len(header))
+	for key := range header {
+		res[key] = header.Get(key)
+	}
+	for _, key := range scrubbedHeaders {
+		delete(res, key)
+	}
+	return res
+}
+
+// ScrubJSON replaces the value of every field that holds a secret with
+// "REDACTED", at any depth. Invalid JSON is returned unchanged.
+func ScrubJSON(data []byte) json.RawMessage {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return data
+	}
+
+	scrubbed, err := json.Marshal(scrubValue(value))
+	if err != nil {
+		return data
+	}
+
+	return scrubbed
+}
+
+func scrubValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if isSecretField(key) && holdsSecret(field) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = scrubValue(field)
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = scrubValue(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// isSecretField reports whether a JSON field name holds a credential, such as
+// user passwords, database authentication_*_pass fields, JWTs and private keys.
+// Fields describing a credential rather than holding it, like
+// password_issue_date, are kept.
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, suffix := range []string{"_date", "_time", "_duration", "_policy", "_complexity"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+
+	for _, part := range []string{"password", "passwd", "token", "secret", "jwt"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+
+	return name == "key" || strings.HasSuffix(name, "_pass") || strings.HasSuffix(name, "_key")
+}
+
+// holdsSecret reports whether a value can carry a credential: a string or a
+// list of strings. Flags and counters next to a credential are kept.
+func holdsSecret(value any) bool {
+	switch v := value.(type) {
+	case string:
+		return v != ""
+	case []any:
+		for _, item := range v {
+			if _, ok := item.(string); ok {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-redis/test/fakeserver"
+)
+
+func TestScrubJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "user password",
+			input:    `{"name":"a","password":"secret","password_issue_date":"2025-02-25T13:46:12Z"}`,
+			expected: `{"name":"a","password":"REDACTED","password_issue_date":"2025-02-25T13:46:12Z"}`,
+		},
+		{
+			name:     "nested database passwords",
+			input:    `[{"uid":1,"authentication_redis_pass":"p","authentication_sasl_pass":"","roles_permissions":[{"role_uid":1}]}]`,
+			expected: `[{"authentication_redis_pass":"REDACTED","authentication_sasl_pass":"","roles_permissions":[{"role_uid":1}],"uid":1}]`,
+		},
+		{
+			name:     "tokens and keys",
+			input:    `{"access_token":"t","jwt":"j","proxy_key":"k","proxy_cert":"c","passwords":["a","b"]}`,
+			expected: `{"access_token":"REDACTED","jwt":"REDACTED","passwords":"REDACTED","proxy_cert":"c","proxy_key":"REDACTED"}`,
+		},
+		{
+			name:     "flags next to credentials",
+			input:    `{"password_complexity":true,"password_expiration_duration":90,"token_count":2}`,
+			expected: `{"password_complexity":true,"password_expiration_duration":90,"token_count":2}`,
+		},
+		{
+			name:     "not json",
+			input:    `password=secret`,
+			expected: `password=secret`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := string(ScrubJSON([]byte(tc.input))); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRecordingTransport(t *testing.T) {
+	server := fakeserver.NewWithDefaults("admin@example.com", "admin-password").Start()
+	t.Cleanup(server.Close)
+
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	recorder, err := NewRecordingTransport(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	httpClient := &http.Client{Transport: recorder}
+
+	body := `{"name":"new","email":"new@example.com","password":"new-password","role":"db_viewer"}`
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/users", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	req.SetBasicAuth("admin@example.com", "admin-password")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The caller still receives the original response.
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(respBody), "new@example.com") {
+		t.Fatalf("Expected the created user, got %d %s", resp.StatusCode, respBody)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, FixtureName(http.MethodPost, "/v1/users", "")))
+	if err != nil {
+		t.Fatalf("Expected a fixture, got %v", err)
+	}
+	for _, secret := range []string{"new-password", "admin-password", "Authorization", "Basic "} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to be scrubbed from %s", secret, data)
+		}
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fixture.Request.Method != http.MethodPost || fixture.Request.Path != "/v1/users" || fixture.Response.Status != http.StatusOK {
+		t.Errorf("Unexpected fixture %+v", fixture)
+	}
+	var requestBody map[string]any
+	if err := json.Unmarshal(fixture.Request.Body, &requestBody); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if requestBody["password"] != "REDACTED" || requestBody["email"] != "new@example.com" {
+		t.Errorf("Expected only the request password to be redacted, got %v", requestBody)
+	}
+}
+
+func TestFixtureName(t *testing.T) {
+	testCases := map[string]string{
+		FixtureName(http.MethodGet, "/v1/users", ""):                    "GET_v1_users.json",
+		FixtureName(http.MethodGet, "/v1/roles/3", ""):                  "GET_v1_roles_3.json",
+		FixtureName(http.MethodGet, "/v1/logs", "stime=2025-01-01&x=y"): "GET_v1_logs_stime_2025-01-01_x_y.json",
+	}
+
+	for got, expected := range testCases {
+		if got != expected {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const incrementalLogPageSize = 1000
+
+var errLogTruncated = errors.New("the event log no longer goes back to the previous sync")
+
+// syncState is what the next sync needs to only fetch the objects changed
+// since a sync: its position in the event log, the time of the newest log
+// entry when it started, and the users, roles and databases it read from then
+// on. Writes during the sync don't make it stale, the event log records them
+// after the watermark.
+type syncState struct {
+	Since     time.Time  `json:"since"`
+	Taken     time.Time  `json:"taken"`
+	Users     []User     `json:"users,omitempty"`
+	Roles     []Role     `json:"roles,omitempty"`
+	Databases []Database `json:"databases,omitempty"`
+}
+
+func (s *syncState) empty() bool {
+	return s.Users == nil && s.Roles == nil && s.Databases == nil
+}
+
+// logChanges are the objects the event log reports as changed, by uid. A
+// collection is marked complete when a change can't be traced to an object.
+type logChanges struct {
+	users         map[int]bool
+	roles         map[int]bool
+	databases     map[int]bool
+	allUsers      bool
+	allRoles      bool
+	allDatabases  bool
+	changeEntries int
+}
+
+func (ch *logChanges) add(entry LogEntry) {
+	mark := func(uids map[int]bool, uid ObjectUID, all *bool) {
+		n, err := strconv.Atoi(string(uid))
+		if err != nil {
+			*all = true
+			return
+		}
+		uids[n] = true
+	}
+
+	switch {
+	case strings.HasPrefix(entry.Type, "user_"):
+		mark(ch.users, entry.UserUID, &ch.allUsers)
+	case strings.HasPrefix(entry.Type, "role_"):
+		mark(ch.roles, entry.RoleUID, &ch.allRoles)
+		if strings.HasSuffix(entry.Type, "_deleted") {
+			// Deleting a role changes the role_uids of its members.
+			ch.allUsers = true
+		}
+	case strings.HasPrefix(entry.Type, "bdb_"):
+		mark(ch.databases, entry.BdbUID, &ch.allDatabases)
+	default:
+		return
+	}
+	ch.changeEntries++
+}
+
+// StartSync prepares the client for a new sync.
+//
+// Without incremental syncs it drops everything cached so far. With them, the
+// users, roles and databases read by the previous sync, of this client or
+// saved to IncrementalStateFile by a previous run, are kept and only the
+// objects the event log reports as changed since are fetched again.
+// Everything is read again when there was no previous sync, when it is older
+// than IncrementalMaxAge, or when the event log was truncated since.
+func (c *RedisClient) StartSync(ctx context.Context) {
+	l := ctxzap.Extract(ctx)
+
+	c.syncMutex.Lock()
+	defer c.syncMutex.Unlock()
+
+	c.stateMutex.Lock()
+	last := c.state
+	c.state = nil
+	c.stateMutex.Unlock()
+	c.InvalidateCache(ctx)
+
+	if c.IncrementalMaxAge <= 0 {
+		return
+	}
+	if last == nil {
+		last = c.loadState(ctx)
+	}
+
+	now := time.Now()
+	head, _, err := c.ListLogs(ctx, LogQuery{Until: now, Limit: 1, Newest: true})
+	if err != nil {
+		l.Warn("baton-redis: unable to read the event log, syncing everything", zap.Error(err))
+		return
+	}
+	if len(head) == 0 {
+		return
+	}
+	c.stateMutex.Lock()
+	c.state = &syncState{Since: head[0].Time.UTC().Truncate(time.Second), Taken: now}
+	c.stateMutex.Unlock()
+
+	switch {
+	case last == nil || last.empty():
+		return
+	case now.Sub(last.Taken) > c.IncrementalMaxAge:
+		l.Info("baton-redis: previous sync is too old, syncing everything", zap.Time("previous_sync", last.Taken))
+		return
+	}
+
+	changes, err := c.changesSince(ctx, last.Since, now)
+	if err != nil {
+		l.Info("baton-redis: unable to read the changes since the previous sync, syncing everything", zap.Error(err))
+		return
+	}
+
+	c.refresh(ctx, last, changes)
+}
+
+// keep records the users, roles or databases listed under key by the current
+// sync for the next one, and saves them to IncrementalStateFile.
+func (c *RedisClient) keep(ctx context.Context, key string, value any) {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	if c.state == nil {
+		return
+	}
+
+	switch v := value.(type) {
+	case []User:
+		c.state.Users = v
+	case []Role:
+		c.state.Roles = v
+	case []Database:
+		c.state.Databases = v
+	default:
+		return
+	}
+	c.saveState(ctx, key)
+}
+
+// loadState reads the state saved by the previous run from
+// IncrementalStateFile, with its secrets redacted, see scrubbedState. It
+// returns nil when there is none or it can't be read.
+func (c *RedisClient) loadState(ctx context.Context) *syncState {
+	if c.IncrementalStateFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.IncrementalStateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	var state syncState
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil {
+		ctxzap.Extract(ctx).Warn(
+			"baton-redis: unable to read the state of the previous sync, syncing everything",
+			zap.String("file", c.IncrementalStateFile),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	return &state
+}
+
+// saveState writes the current state to IncrementalStateFile, replacing the
+// previous one at once. The caller holds stateMutex. A failure only costs the
+// next run an incremental sync.
+func (c *RedisClient) saveState(ctx context.Context, key string) {
+	if c.IncrementalStateFile == "" {
+		return
+	}
+
+	err := func() error {
+		data, err := scrubbedState(c.state)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(c.IncrementalStateFile), 0o700); err != nil {
+			return err
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(c.IncrementalStateFile), filepath.Base(c.IncrementalStateFile)+".*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := tmp.Write(data); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), c.IncrementalStateFile)
+	}()
+	if err != nil {
+		ctxzap.Extract(ctx).Warn(
+			"baton-redis: unable to save the sync state, the next run syncs everything",
+			zap.String("file", c.IncrementalStateFile),
+			zap.String("objects", key),
+			zap.Error(err),
+		)
+	}
+}
+
+// scrubbedState encodes state with the value of every field holding a secret,
+// such as the authentication_redis_pass of databases, replaced by "REDACTED",
+// so no credential is written to disk. The connector only checks whether those
+// fields are set, which the placeholder keeps.
+func scrubbedState(state *syncState) ([]byte, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(scrubValue(value))
+}
+
+// changesSince reads the event log from since, the watermark of the previous
+// sync, until now.
+func (c *RedisClient) changesSince(ctx context.Context, since time.Time, now time.Time) (*logChanges, error) {
+	changes := &logChanges{
+		users:     make(map[int]bool),
+		roles:     make(map[int]bool),
+		databases: make(map[int]bool),
+	}
+
+	for offset := 0; ; offset += incrementalLogPageSize {
+		entries, _, err := c.ListLogs(ctx, LogQuery{Since: since, Until: now, Limit: incrementalLogPageSize, Offset: offset})
+		if err != nil {
+			return nil, err
+		}
+
+		// The entry the watermark was taken from must still be there.
+		if offset == 0 && (len(entries) == 0 || !entries[0].Time.UTC().Truncate(time.Second).Equal(since)) {
+			return nil, errLogTruncated
+		}
+
+		for _, entry := range entries {
+			changes.add(entry)
+		}
+
+		if len(entries) < incrementalLogPageSize {
+			return changes, nil
+		}
+	}
+}
+
+// refresh caches the objects of the previous sync, with the changed ones
+// fetched again. Collections with more changes than unchanged objects are
+// left out, listing them again is cheaper.
+func (c *RedisClient) refresh(ctx context.Context, previous *syncState, changes *logChanges) {
+	l := ctxzap.Extract(ctx)
+
+	if users := previous.Users; users != nil && !changes.allUsers && len(changes.users) <= len(users)/2+1 {
+		merged, err := refreshed(ctx, users, changes.users, func(u User) int { return u.UID }, c.GetUser)
+		if err != nil {
+			l.Warn("baton-redis: unable to refresh changed users, listing all users", zap.Error(err))
+		} else {
+			c.cache.set(getUsers, merged)
+			c.keep(ctx, getUsers, merged)
+		}
+	}
+
+	if roles := previous.Roles; roles != nil && !changes.allRoles && len(changes.roles) <= len(roles)/2+1 {
+		getRole := func(ctx context.Context, uid int) (Role, annotations.Annotations, error) {
+			return c.GetRoleDetails(ctx, strconv.Itoa(uid))
+		}
+		merged, err := refreshed(ctx, roles, changes.roles, func(r Role) int { return r.UID }, getRole)
+		if err != nil {
+			l.Warn("baton-redis: unable to refresh changed roles, listing all roles", zap.Error(err))
+		} else {
+			c.cache.set(getRoles, merged)
+			c.keep(ctx, getRoles, merged)
+		}
+	}
+
+	if databases := previous.Databases; databases != nil && !changes.allDatabases && len(changes.databases) <= len(databases)/2+1 {
+		merged, err := refreshed(ctx, databases, changes.databases, func(d Database) int { return d.UID }, c.GetDatabase)
+		if err != nil {
+			l.Warn("baton-redis: unable to refresh changed databases, listing all databases", zap.Error(err))
+		} else {
+			c.cache.set(getBdbs, merged)
+			c.keep(ctx, getBdbs, merged)
+		}
+	}
+
+	l.Info(
+		"baton-redis: incremental sync",
+		zap.Int("log_entries", changes.changeEntries),
+		zap.Int("changed_users", len(changes.users)),
+		zap.Int("changed_roles", len(changes.roles)),
+		zap.Int("changed_databases", len(changes.databases)),
+	)
+}
+
+// refreshed returns previous with the objects in changed fetched again. Objects
+// that no longer exist are dropped and new ones are appended.
+func refreshed[T any](
+	ctx context.Context,
+	previous []T,
+	changed map[int]bool,
+	uid func(T) int,
+	fetch func(ctx context.Context, uid int) (T, annotations.Annotations, error),
+) ([]T, error) {
+	res := make([]T, 0, len(previous)+len(changed))
+	known := make(map[int]bool, len(previous))
+
+	get := func(id int) (T, bool, error) {
+		obj, _, err := fetch(ctx, id)
+		if status.Code(err) == codes.NotFound {
+			return obj, false, nil
+		}
+		if err != nil {
+			return obj, false, fmt.Errorf("uid %d: %w", id, err)
+		}
+		return obj, true, nil
+	}
+
+	for _, obj := range previous {
+		id := uid(obj)
+		known[id] = true
+		if !changed[id] {
+			res = append(res, obj)
+			continue
+		}
+
+		updated, ok, err := get(id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res = append(res, updated)
+		}
+	}
+
+	var added []int
+	for id := range changed {
+		if !known[id] {
+			added = append(added, id)
+		}
+	}
+	sort.Ints(added)
+
+	for _, id := range added {
+		created, ok, err := get(id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res = append(res, created)
+		}
+	}
+
+	return res, nil
+}
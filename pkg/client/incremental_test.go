@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+// syncRequests starts a sync, lists users and roles like the role builder
+// does and returns the requests the cluster received.
+func syncRequests(t *testing.T, fake *fakeserver.Server, client *RedisClient) ([]string, []User) {
+	t.Helper()
+
+	ctx := context.Background()
+	before := len(fake.Requests())
+
+	client.StartSync(ctx)
+	users, _, err := client.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, _, err := client.ListRoles(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	return fake.Requests()[before:], users
+}
+
+func TestRedisClient_IncrementalSync(t *testing.T) {
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	at := func(minutes int) string {
+		return base.Add(time.Duration(minutes) * time.Minute).Format(time.RFC3339)
+	}
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.AddLog(fakeserver.Object{"time": at(0), "type": "cluster_created"})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	client := NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.IncrementalMaxAge = time.Hour
+
+	requests, _ := syncRequests(t, fake, client)
+	expected := []string{"GET /v1/logs", "GET /v1/users", "GET /v1/roles"}
+	if !slices.Equal(requests, expected) {
+		t.Fatalf("Expected a full first sync %v, got %v", expected, requests)
+	}
+
+	// Nothing changed: only the log is read.
+	requests, _ = syncRequests(t, fake, client)
+	expected = []string{"GET /v1/logs", "GET /v1/logs"}
+	if !slices.Equal(requests, expected) {
+		t.Fatalf("Expected an incremental sync %v, got %v", expected, requests)
+	}
+
+	// One user changed: only that user is fetched again.
+	if err := fake.Update(fakeserver.Users, 2, fakeserver.Object{"name": "Renamed"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	fake.AddLog(fakeserver.Object{"time": at(1), "type": "user_updated", "user_uid": 2})
+
+	requests, users := syncRequests(t, fake, client)
+	expected = []string{"GET /v1/logs", "GET /v1/logs", "GET /v1/users/2"}
+	if !slices.Equal(requests, expected) {
+		t.Fatalf("Expected only the changed user to be fetched %v, got %v", expected, requests)
+	}
+	if len(users) != 2 || users[1].Name != "Renamed" {
+		t.Errorf("Expected the renamed user, got %+v", users)
+	}
+
+	// A deleted role can change any user.
+	fake.AddLog(fakeserver.Object{"time": at(2), "type": "role_deleted", "role_uid": 3})
+
+	requests, _ = syncRequests(t, fake, client)
+	expected = []string{"GET /v1/logs", "GET /v1/logs", "GET /v1/roles/3", "GET /v1/users"}
+	if !slices.Equal(requests, expected) {
+		t.Fatalf("Expected users to be listed again %v, got %v", expected, requests)
+	}
+
+	// Once the log no longer reaches the previous sync everything is listed.
+	fake.TruncateLogs(base.Add(3 * time.Minute))
+	fake.AddLog(fakeserver.Object{"time": at(3), "type": "cluster_updated"})
+
+	requests, _ = syncRequests(t, fake, client)
+	expected = []string{"GET /v1/logs", "GET /v1/logs", "GET /v1/users", "GET /v1/roles"}
+	if !slices.Equal(requests, expected) {
+		t.Fatalf("Expected a full sync after the log was truncated %v, got %v", expected, requests)
+	}
+}
+
+func TestRedisClient_IncrementalSyncDisabled(t *testing.T) {
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.AddLog(fakeserver.Object{"type": "cluster_created"})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	client := NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+
+	for range 2 {
+		requests, _ := syncRequests(t, fake, client)
+		expected := []string{"GET /v1/users", "GET /v1/roles"}
+		if !slices.Equal(requests, expected) {
+			t.Fatalf("Expected full syncs %v, got %v", expected, requests)
+		}
+	}
+}
+
+func TestRedisClient_IncrementalSyncAcrossRuns(t *testing.T) {
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.AddLog(fakeserver.Object{"time": time.Now().Add(-time.Minute).UTC().Format(time.RFC3339), "type": "cluster_created"})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	stateFile := filepath.Join(t.TempDir(), "cluster", "incremental-sync.json")
+	newClient := func() *RedisClient {
+		client := NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+		client.IncrementalMaxAge = time.Hour
+		client.IncrementalStateFile = stateFile
+		return client
+	}
+
+	requests, _ := syncRequests(t, fake, newClient())
+	expected := []string{"GET /v1/logs", "GET /v1/users", "GET /v1/roles"}
+	if !slices.Equal(requests, expected) {
+		t.Fatalf("Expected a full first sync %v, got %v", expected, requests)
+	}
+
+	// A new run picks up from the state saved by the previous one.
+	requests, users := syncRequests(t, fake, newClient())
+	expected = []string{"GET /v1/logs", "GET /v1/logs"}
+	if !slices.Equal(requests, expected) {
+		t.Fatalf("Expected an incremental sync %v, got %v", expected, requests)
+	}
+	if len(users) != 2 {
+		t.Errorf("Expected the 2 users of the previous run, got %+v", users)
+	}
+}
+
+func TestRedisClient_IncrementalSyncStateHasNoSecrets(t *testing.T) {
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.AddLog(fakeserver.Object{"time": time.Now().Add(-time.Minute).UTC().Format(time.RFC3339), "type": "cluster_created"})
+	if err := fake.Update(fakeserver.Databases, 1, fakeserver.Object{
+		"authentication_redis_pass": "shared-secret",
+		"authentication_sasl_pass":  "sasl-secret",
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	stateFile := filepath.Join(t.TempDir(), "incremental-sync.json")
+	client := NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.IncrementalMaxAge = time.Hour
+	client.IncrementalStateFile = stateFile
+
+	ctx := context.Background()
+	client.StartSync(ctx)
+	databases, _, err := client.ListDatabases(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if databases[0].AuthenticationRedisPass != "shared-secret" {
+		t.Fatalf("Expected the database password to be read, got %+v", databases[0])
+	}
+
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Expected no secret in the state file, got %s", data)
+	}
+
+	var state map[string]any
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, database := range state["databases"].([]any) {
+		for name, value := range database.(map[string]any) {
+			if strings.HasSuffix(name, "_pass") && value != redacted {
+				t.Errorf("Expected %s to be redacted, got %v", name, value)
+			}
+		}
+	}
+}
+
+func TestRedisClient_IncrementalSyncSurvivesWrites(t *testing.T) {
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.AddLog(fakeserver.Object{"time": time.Now().Add(-time.Minute).UTC().Format(time.RFC3339), "type": "cluster_created"})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	client := NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+	client.IncrementalMaxAge = time.Hour
+
+	syncRequests(t, fake, client)
+
+	// A grant between syncs drops the cache, not what the next sync builds on.
+	if _, _, err := client.UpdateUser(context.Background(), 2, map[string]any{"name": "Renamed"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	requests, users := syncRequests(t, fake, client)
+	expected := []string{"GET /v1/logs", "GET /v1/logs", "GET /v1/users/2"}
+	if !slices.Equal(requests, expected) {
+		t.Fatalf("Expected only the changed user to be fetched %v, got %v", expected, requests)
+	}
+	if len(users) != 2 || users[1].Name != "Renamed" {
+		t.Errorf("Expected the renamed user, got %+v", users)
+	}
+}
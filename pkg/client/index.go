@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+const roleIndexKey = "index:roles"
+
+// RoleIndex holds the cluster roles and, for each of them, the users holding
+// the role. It is built once per sync and shared by every caller, so it must
+// not be modified.
+type RoleIndex struct {
+	Roles map[int]Role
+	Users map[int][]User
+}
+
+// GetRoleIndex returns the roles and role members of the cluster, built from
+// a single listing of the users and the roles.
+func (c *RedisClient) GetRoleIndex(ctx context.Context) (*RoleIndex, annotations.Annotations, error) {
+	return cached(ctx, c.cache, roleIndexKey, func(ctx context.Context) (*RoleIndex, annotations.Annotations, error) {
+		roles, annotation, err := c.ListRoles(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		users, _, err := c.ListUsers(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return NewRoleIndex(roles, users), annotation, nil
+	})
+}
+
+func NewRoleIndex(roles []Role, users []User) *RoleIndex {
+	index := &RoleIndex{
+		Roles: make(map[int]Role, len(roles)),
+		Users: make(map[int][]User, len(roles)),
+	}
+
+	for _, role := range roles {
+		index.Roles[role.UID] = role
+	}
+
+	for _, user := range users {
+		for _, roleUID := range user.RoleUIDs {
+			index.Users[roleUID] = append(index.Users[roleUID], user)
+		}
+	}
+
+	return index
+}
@@ -2,23 +2,239 @@ package client
 
 import "time"
 
+// User is a cluster user, see
+// https://redis.io/docs/latest/operate/rs/references/rest-api/objects/user/.
 type User struct {
-	AuthMethod             string        `json:"auth_method"`
-	BdbsEmailAlerts        []interface{} `json:"bdbs_email_alerts"`
-	CertificateSubjectLine string        `json:"certificate_subject_line"`
-	ClusterEmailAlerts     bool          `json:"cluster_email_alerts"`
-	Email                  string        `json:"email"`
-	EmailAlerts            bool          `json:"email_alerts"`
-	Name                   string        `json:"name"`
-	PasswordIssueDate      time.Time     `json:"password_issue_date"`
-	Role                   string        `json:"role"`
-	RoleUIDs               []int         `json:"role_uids"`
-	Status                 string        `json:"status"`
-	UID                    int           `json:"uid"`
+	UID                        int       `json:"uid"`
+	Name                       string    `json:"name"`
+	Email                      string    `json:"email"`
+	AccountID                  int       `json:"account_id,omitempty"`
+	ActionUID                  string    `json:"action_uid,omitempty"`
+	AuthMethod                 string    `json:"auth_method"`
+	CertificateSubjectLine     string    `json:"certificate_subject_line"`
+	Role                       string    `json:"role"`
+	RoleUIDs                   []int     `json:"role_uids"`
+	Status                     string    `json:"status"`
+	PasswordIssueDate          time.Time `json:"password_issue_date"`
+	PasswordExpirationDuration int       `json:"password_expiration_duration,omitempty"`
+	EmailAlerts                bool      `json:"email_alerts"`
+	ClusterEmailAlerts         bool      `json:"cluster_email_alerts"`
+	// BdbsEmailAlerts are the uids of the databases the user receives email
+	// alerts for.
+	BdbsEmailAlerts UIDList `json:"bdbs_email_alerts,omitempty"`
+	Extra           Extra   `json:"-"`
 }
 
+func (u *User) UnmarshalJSON(data []byte) error {
+	type user User
+	var res user
+	extra, err := unmarshalWithExtra(data, &res)
+	if err != nil {
+		return err
+	}
+	*u = User(res)
+	u.Extra = extra
+	return nil
+}
+
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User
+	return marshalWithExtra(user(u), u.Extra)
+}
+
+// Role is a cluster role, see
+// https://redis.io/docs/latest/operate/rs/references/rest-api/objects/role/.
 type Role struct {
-	Management string `json:"management"`
-	Name       string `json:"name"`
 	UID        int    `json:"uid"`
+	Name       string `json:"name"`
+	Management string `json:"management"`
+	AccountID  int    `json:"account_id,omitempty"`
+	ActionUID  string `json:"action_uid,omitempty"`
+	Extra      Extra  `json:"-"`
+}
+
+func (r *Role) UnmarshalJSON(data []byte) error {
+	type role Role
+	var res role
+	extra, err := unmarshalWithExtra(data, &res)
+	if err != nil {
+		return err
+	}
+	*r = Role(res)
+	r.Extra = extra
+	return nil
+}
+
+func (r Role) MarshalJSON() ([]byte, error) {
+	type role Role
+	return marshalWithExtra(role(r), r.Extra)
+}
+
+// RolePermission gives a role the permissions of a Redis ACL on a database.
+type RolePermission struct {
+	RoleUID     int `json:"role_uid"`
+	RedisACLUID int `json:"redis_acl_uid"`
+}
+
+// RedisACL is a named set of Redis ACL rules roles are given on databases, see
+// https://redis.io/docs/latest/operate/rs/references/rest-api/objects/redis_acl/.
+type RedisACL struct {
+	UID   int    `json:"uid"`
+	Name  string `json:"name"`
+	ACL   string `json:"acl"`
+	Extra Extra  `json:"-"`
+}
+
+func (a *RedisACL) UnmarshalJSON(data []byte) error {
+	type redisACL RedisACL
+	var res redisACL
+	extra, err := unmarshalWithExtra(data, &res)
+	if err != nil {
+		return err
+	}
+	*a = RedisACL(res)
+	a.Extra = extra
+	return nil
+}
+
+func (a RedisACL) MarshalJSON() ([]byte, error) {
+	type redisACL RedisACL
+	return marshalWithExtra(redisACL(a), a.Extra)
+}
+
+// DatabaseEndpoint is an address clients connect to a database through.
+type DatabaseEndpoint struct {
+	UID              string   `json:"uid"`
+	Addr             []string `json:"addr"`
+	DNSAddressMaster string   `json:"dns_address_master"`
+	Port             int      `json:"port"`
+}
+
+// ClientCertificate is a certificate a database accepts clients with.
+type ClientCertificate struct {
+	ClientCert string `json:"client_cert"`
+}
+
+// CertificateSubject is the subject a client certificate must have for a
+// database to accept it, see Database.ClientCertSubjectValidationType.
+type CertificateSubject struct {
+	CN string   `json:"CN,omitempty"`
+	O  string   `json:"O,omitempty"`
+	OU []string `json:"OU,omitempty"`
+	L  string   `json:"L,omitempty"`
+	ST string   `json:"ST,omitempty"`
+	C  string   `json:"C,omitempty"`
+}
+
+// Database is a Redis Enterprise database (BDB), see
+// https://redis.io/docs/latest/operate/rs/references/rest-api/objects/bdb/.
+type Database struct {
+	UID              int                `json:"uid"`
+	Name             string             `json:"name"`
+	Type             string             `json:"type"`
+	Status           string             `json:"status"`
+	Version          string             `json:"version"`
+	Port             int                `json:"port"`
+	MemorySize       int64              `json:"memory_size"`
+	ShardsCount      int                `json:"shards_count"`
+	Replication      bool               `json:"replication"`
+	Endpoints        []DatabaseEndpoint `json:"endpoints,omitempty"`
+	RolesPermissions []RolePermission   `json:"roles_permissions"`
+	// DefaultUser is whether clients can connect as the default user, with
+	// AuthenticationRedisPass as the password when it is set.
+	DefaultUser                  bool                `json:"default_user"`
+	AuthenticationRedisPass      string              `json:"authentication_redis_pass,omitempty"`
+	TLSMode                      string              `json:"tls_mode,omitempty"`
+	EnforceClientAuthentication  string              `json:"enforce_client_authentication,omitempty"`
+	AuthenticationSSLClientCerts []ClientCertificate `json:"authentication_ssl_client_certs,omitempty"`
+	// ClientCertSubjectValidationType is how the subject of client
+	// certificates is checked: disabled, san_cn against the CN of
+	// AuthorizedSubjects, or full_subject against all of it. AuthorizedNames
+	// are the CNs checked before subject validation existed.
+	ClientCertSubjectValidationType string               `json:"client_cert_subject_validation_type,omitempty"`
+	AuthorizedSubjects              []CertificateSubject `json:"authorized_subjects,omitempty"`
+	AuthorizedNames                 []string             `json:"authorized_names,omitempty"`
+	CRDT                            bool                 `json:"crdt"`
+	CRDTGUID                        string               `json:"crdt_guid,omitempty"`
+	EmailAlerts                     bool                 `json:"email_alerts"`
+	CreatedTime                     string               `json:"created_time,omitempty"`
+	LastChangedTime                 string               `json:"last_changed_time,omitempty"`
+	Extra                           Extra                `json:"-"`
+}
+
+func (d *Database) UnmarshalJSON(data []byte) error {
+	type database Database
+	var res database
+	extra, err := unmarshalWithExtra(data, &res)
+	if err != nil {
+		return err
+	}
+	*d = Database(res)
+	d.Extra = extra
+	return nil
+}
+
+func (d Database) MarshalJSON() ([]byte, error) {
+	type database Database
+	return marshalWithExtra(database(d), d.Extra)
+}
+
+type Cluster struct {
+	Name string `json:"name"`
+	// PasswordExpirationDuration is the number of days a password is valid
+	// for, 0 when passwords don't expire.
+	PasswordExpirationDuration int  `json:"password_expiration_duration"`
+	PasswordComplexity         bool `json:"password_complexity"`
+	// PasswordMinLength is 0 on versions that don't report it, where the
+	// minimum is 8.
+	PasswordMinLength int `json:"password_min_length,omitempty"`
+	// LoginLockoutThreshold is the number of failed logins locking a user
+	// out, 0 when users are never locked out. LoginLockoutDuration is how
+	// many seconds they stay locked out, 0 until an admin unlocks them.
+	LoginLockoutThreshold int `json:"login_lockout_threshold"`
+	LoginLockoutDuration  int `json:"login_lockout_duration"`
+	// LoginLockoutCounterResetAfter is how many seconds failed logins count
+	// towards the threshold.
+	LoginLockoutCounterResetAfter int    `json:"login_lockout_counter_reset_after"`
+	MinControlTLSVersion          string `json:"min_control_TLS_version,omitempty"`
+	MinDataTLSVersion             string `json:"min_data_TLS_version,omitempty"`
+}
+
+// LDAP is the LDAP configuration of the cluster, see
+// https://redis.io/docs/latest/operate/rs/references/rest-api/objects/ldap/.
+type LDAP struct {
+	URIs         []string `json:"uris"`
+	ControlPlane bool     `json:"control_plane"`
+	DataPlane    bool     `json:"data_plane"`
+}
+
+// Enabled reports whether users can sign in to the cluster or its databases
+// through LDAP.
+func (l LDAP) Enabled() bool {
+	return len(l.URIs) > 0 && (l.ControlPlane || l.DataPlane)
+}
+
+// LDAPMapping gives the roles of the cluster to the members of an LDAP group,
+// see https://redis.io/docs/latest/operate/rs/references/rest-api/objects/ldap_mapping/.
+type LDAPMapping struct {
+	UID      int    `json:"uid"`
+	Name     string `json:"name"`
+	DN       string `json:"dn"`
+	RoleUIDs []int  `json:"role_uids"`
+}
+
+type Node struct {
+	UID          int      `json:"uid"`
+	Addr         string   `json:"addr"`
+	ExternalAddr []string `json:"external_addr"`
+	Status       string   `json:"status"`
+}
+
+// Address returns the address the connector should use to reach the node,
+// preferring the external address when the node has one.
+func (n Node) Address() string {
+	if len(n.ExternalAddr) > 0 && n.ExternalAddr[0] != "" {
+		return n.ExternalAddr[0]
+	}
+	return n.Addr
 }
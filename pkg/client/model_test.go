@@ -0,0 +1,132 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestUser_PreservesUnknownFields(t *testing.T) {
+	data := `{
+		"uid": 7,
+		"name": "alice",
+		"email": "alice@example.com",
+		"account_id": 42,
+		"role": "db_viewer",
+		"role_uids": [2],
+		"password_issue_date": "2025-02-25T13:46:12Z",
+		"password_expiration_duration": 90,
+		"bdbs_email_alerts": ["1", 2],
+		"alert_audit_db_conns": true,
+		"future_field": {"nested": [1, 2]}
+	}`
+
+	var user User
+	if err := json.Unmarshal([]byte(data), &user); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if user.UID != 7 || user.AccountID != 42 || user.PasswordExpirationDuration != 90 {
+		t.Errorf("Unexpected user %+v", user)
+	}
+	if !reflect.DeepEqual(user.BdbsEmailAlerts, UIDList{"1", "2"}) {
+		t.Errorf("Expected database uids [1 2], got %v", user.BdbsEmailAlerts)
+	}
+	if len(user.Extra) != 2 || string(user.Extra["alert_audit_db_conns"]) != "true" {
+		t.Errorf("Expected the two unknown fields to be kept, got %v", user.Extra)
+	}
+
+	encoded, err := json.Marshal(user)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var roundTrip map[string]any
+	if err := json.Unmarshal(encoded, &roundTrip); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if roundTrip["future_field"] == nil || roundTrip["alert_audit_db_conns"] != true || roundTrip["name"] != "alice" {
+		t.Errorf("Expected unknown fields to be written back, got %s", encoded)
+	}
+	if _, ok := roundTrip["Extra"]; ok {
+		t.Errorf("Expected Extra not to be encoded as a field, got %s", encoded)
+	}
+}
+
+func TestRole_WithoutUnknownFields(t *testing.T) {
+	var role Role
+	if err := json.Unmarshal([]byte(`{"uid": 1, "name": "Admin", "management": "admin"}`), &role); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := Role{UID: 1, Name: "Admin", Management: "admin"}
+	if !reflect.DeepEqual(role, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, role)
+	}
+}
+
+func TestDatabase_Unmarshal(t *testing.T) {
+	data := `{
+		"uid": 1,
+		"name": "db1",
+		"type": "redis",
+		"port": 12000,
+		"default_user": true,
+		"roles_permissions": [{"role_uid": 1, "redis_acl_uid": 2}],
+		"endpoints": [{"uid": "1:1", "addr": ["10.0.0.1"], "dns_address_master": "db1.example.com", "port": 12000}],
+		"authentication_ssl_client_certs": [{"client_cert": "-----BEGIN CERTIFICATE-----"}],
+		"sharding": false
+	}`
+
+	var database Database
+	if err := json.Unmarshal([]byte(data), &database); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if database.Name != "db1" || !database.DefaultUser || database.Port != 12000 {
+		t.Errorf("Unexpected database %+v", database)
+	}
+	if !reflect.DeepEqual(database.RolesPermissions, []RolePermission{{RoleUID: 1, RedisACLUID: 2}}) {
+		t.Errorf("Unexpected roles permissions %+v", database.RolesPermissions)
+	}
+	if len(database.Endpoints) != 1 || database.Endpoints[0].DNSAddressMaster != "db1.example.com" {
+		t.Errorf("Unexpected endpoints %+v", database.Endpoints)
+	}
+	if len(database.AuthenticationSSLClientCerts) != 1 {
+		t.Errorf("Unexpected client certificates %+v", database.AuthenticationSSLClientCerts)
+	}
+	if string(database.Extra["sharding"]) != "false" {
+		t.Errorf("Expected sharding to be kept, got %v", database.Extra)
+	}
+}
+
+func TestCRDB_Unmarshal(t *testing.T) {
+	data := `{
+		"guid": "1c6b1e9b-6b1e-4c1e-9c1e-1c6b1e9b6b1e",
+		"name": "sessions",
+		"encryption": true,
+		"instances": [
+			{"id": 1, "cluster": {"name": "east.example.com", "url": "https://east.example.com:9443"}, "db_uid": "3", "compression": 3},
+			{"id": 2, "cluster": {"name": "west.example.com", "url": "https://west.example.com:9443"}, "db_uid": 5}
+		],
+		"local_databases": [{"id": 1, "bdb_uid": 3}],
+		"featureset_version": 5
+	}`
+
+	var crdb CRDB
+	if err := json.Unmarshal([]byte(data), &crdb); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(crdb.Instances) != 2 || crdb.Instances[1].DBUID != "5" || crdb.Instances[0].Cluster.Name != "east.example.com" {
+		t.Errorf("Unexpected instances %+v", crdb.Instances)
+	}
+	if string(crdb.Instances[0].Extra["compression"]) != "3" || string(crdb.Extra["featureset_version"]) != "5" {
+		t.Errorf("Expected unknown fields to be kept, got %v and %v", crdb.Instances[0].Extra, crdb.Extra)
+	}
+	if got := crdb.LocalDatabaseUID(1); got != "3" {
+		t.Errorf("Expected instance 1 to be database 3, got %q", got)
+	}
+	if got := crdb.LocalDatabaseUID(2); got != "" {
+		t.Errorf("Expected instance 2 to be remote, got %q", got)
+	}
+}
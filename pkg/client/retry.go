@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// errTooManyRequests marks the requests a node throttled: the node is fine, the
+// request has to wait before it is sent to it again.
+var errTooManyRequests = errors.New("baton-redis: too many requests")
+
+// doRequestWithRetry retries requests failing with a transient error, such as
+// the 503s and connection resets returned while the cluster fails over its
+// master node. Requests that aren't idempotent are only retried when they
+// never reached the cluster.
+func (c *RedisClient) doRequestWithRetry(
+	ctx context.Context,
+	method string,
+	urlEndpoint string,
+	body interface{},
+	res interface{},
+) (http.Header, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if method != http.MethodGet {
+		// Whatever the outcome, a write may have changed cached objects.
+		defer c.InvalidateCache(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		header, annotation, err := c.doRequestWithFailover(ctx, method, urlEndpoint, body, res)
+		if err == nil || attempt >= c.MaxRetries || !isRetryable(method, err) {
+			return header, annotation, err
+		}
+
+		wait := c.backoff(attempt, header)
+		l.Warn(
+			"baton-redis: transient cluster error, retrying",
+			zap.String("method", method),
+			zap.String("endpoint", urlEndpoint),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("wait", wait),
+			zap.Error(err),
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryable reports whether a failed request can be sent again. A throttled
+// request was refused before being processed, so it is always retried.
+func isRetryable(method string, err error) bool {
+	if errors.Is(err, errTooManyRequests) {
+		return true
+	}
+	if !isNodeFailure(err) {
+		return false
+	}
+
+	return isIdempotent(method) || isConnectError(err)
+}
+
+// isIdempotent reports whether sending a request twice has the same effect as
+// sending it once.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isConnectError reports whether err happened while connecting, in which case
+// the cluster never saw the request.
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// backoff returns how long to wait before the given retry: the Retry-After
+// sent by the cluster when there is one, otherwise an exponential backoff
+// with jitter. Both are capped at the max backoff.
+func (c *RedisClient) backoff(attempt int, header http.Header) time.Duration {
+	initial, maxBackoff := c.InitialBackoff, c.MaxBackoff
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	if retryAfter, ok := parseRetryAfter(header, time.Now()); ok {
+		return min(retryAfter, maxBackoff)
+	}
+
+	wait := maxBackoff
+	if attempt < 32 {
+		if d := initial << attempt; d > 0 && d < maxBackoff {
+			wait = d
+		}
+	}
+
+	// Wait between half and the full backoff so concurrent syncs don't hit
+	// the recovering node at the same time.
+	half := wait / 2
+	return half + rand.N(wait-half+1) //nolint:gosec // jitter doesn't need a secure random source.
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an
+// HTTP date.
+func parseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		if seconds > int(math.MaxInt64/int64(time.Second)) {
+			return math.MaxInt64, true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
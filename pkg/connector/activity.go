@@ -0,0 +1,159 @@
+package connector
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const loginLogPageSize = 1000
+
+// AccountThresholds are how many days a user can go without signing in, or
+// without changing its password, before it is flagged as dormant or its
+// password as stale. A threshold set to 0 isn't checked.
+type AccountThresholds struct {
+	DormantDays       int
+	StalePasswordDays int
+}
+
+// DefaultAccountThresholds flags the accounts unused, or whose password wasn't
+// changed, for 90 days.
+var DefaultAccountThresholds = AccountThresholds{
+	DormantDays:       90,
+	StalePasswordDays: 90,
+}
+
+// loginActivity is what the cluster event log tells about the users signing
+// in: the last login of each user, and the users created in the period read,
+// who can't have been dormant for all of it. complete is set when the log goes
+// back to the beginning of that period, a user without a login can only be
+// told dormant then.
+type loginActivity struct {
+	lastLogin map[int]time.Time
+	created   map[int]bool
+	complete  bool
+}
+
+// readLoginActivity reads the logins and user creations the event log of
+// cluster c recorded from since until now. It returns nil when the log can't
+// be read, the activity of the users is then unknown.
+func readLoginActivity(ctx context.Context, c *cluster, users []client.User, since time.Time, now time.Time) *loginActivity {
+	l := ctxzap.Extract(ctx)
+	until := now.UTC().Truncate(time.Second)
+
+	activity := &loginActivity{
+		lastLogin: make(map[int]time.Time),
+		created:   make(map[int]bool),
+	}
+
+	oldest, _, err := c.client.ListLogs(ctx, client.LogQuery{Until: until, Limit: 1})
+	if err != nil {
+		l.Warn("baton-redis: unable to read the event log, skipping the last login of users", zap.String("cluster", c.id), zap.Error(err))
+		return nil
+	}
+	activity.complete = len(oldest) > 0 && !oldest[0].Time.After(since)
+	if !activity.complete {
+		l.Info(
+			"baton-redis: the event log doesn't go back to the dormancy threshold, no user is flagged as dormant",
+			zap.String("cluster", c.id),
+			zap.Time("since", since),
+		)
+	}
+
+	for offset := 0; ; offset += loginLogPageSize {
+		entries, _, err := c.client.ListLogs(ctx, client.LogQuery{
+			Since:  since,
+			Until:  until,
+			Limit:  loginLogPageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			l.Warn("baton-redis: unable to read the event log, skipping the last login of users", zap.String("cluster", c.id), zap.Error(err))
+			return nil
+		}
+
+		for _, entry := range entries {
+			activity.add(users, entry)
+		}
+
+		if len(entries) < loginLogPageSize {
+			return activity
+		}
+	}
+}
+
+func (a *loginActivity) add(users []client.User, entry client.LogEntry) {
+	switch {
+	case entry.Type == "user_created":
+		if uid, ok := logUser(users, entry); ok {
+			a.created[uid] = true
+		}
+	case isLoginLog(entry.Type):
+		if uid, ok := logUser(users, entry); ok && entry.Time.After(a.lastLogin[uid]) {
+			a.lastLogin[uid] = entry.Time
+		}
+	}
+}
+
+// isLoginLog reports whether a log type records a user signing in, such as
+// user_login.
+func isLoginLog(logType string) bool {
+	return strings.HasSuffix(logType, "login") || strings.HasSuffix(logType, "login_succeeded")
+}
+
+// logUser returns the uid of the user a log entry is about, by uid or else by
+// name.
+func logUser(users []client.User, entry client.LogEntry) (int, bool) {
+	if entry.UserUID != "" {
+		uid, err := strconv.Atoi(string(entry.UserUID))
+		return uid, err == nil
+	}
+
+	user := findUser(users, entry.UserName)
+	if user == nil {
+		return 0, false
+	}
+	return user.UID, true
+}
+
+// accountActivity adds the age of the password of user, its last login and
+// whether it is dormant or its password stale to profile. It returns the last
+// login, the zero time when unknown. activity is nil when the event log
+// wasn't read, the user is then never flagged as dormant, nor when the log
+// doesn't go back to the dormancy threshold.
+func accountActivity(
+	profile map[string]interface{},
+	user *client.User,
+	activity *loginActivity,
+	thresholds AccountThresholds,
+	now time.Time,
+) time.Time {
+	if !user.PasswordIssueDate.IsZero() {
+		age := int(now.Sub(user.PasswordIssueDate) / (24 * time.Hour))
+		profile["password_age_days"] = age
+		if thresholds.StalePasswordDays > 0 {
+			profile["stale_password"] = age >= thresholds.StalePasswordDays
+		}
+	}
+
+	if activity == nil {
+		return time.Time{}
+	}
+
+	lastLogin, ok := activity.lastLogin[user.UID]
+	if ok {
+		profile["last_login"] = lastLogin.UTC().Format(time.RFC3339)
+	}
+	if thresholds.DormantDays > 0 && activity.complete {
+		// The log is read from the dormancy threshold on, so any login found
+		// is recent enough.
+		profile["dormant"] = !ok && !activity.created[user.UID]
+	}
+
+	return lastLogin
+}
@@ -0,0 +1,180 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// emailAlertsEntitlement is the entitlement of the cluster, or of a database,
+// held by the users receiving its email alerts.
+const emailAlertsEntitlement = "email_alerts"
+
+// alertsEntitlement returns the email alerts entitlement of a cluster or
+// database resource.
+func alertsEntitlement(resource *v2.Resource, subject string) *v2.Entitlement {
+	return entitlement.NewPermissionEntitlement(
+		resource,
+		emailAlertsEntitlement,
+		entitlement.WithGrantableTo(userResourceType),
+		entitlement.WithDisplayName(fmt.Sprintf("%s Email Alerts", resource.DisplayName)),
+		entitlement.WithDescription(fmt.Sprintf("Receive the email alerts of %s", subject)),
+	)
+}
+
+// receivesClusterAlerts reports whether the user receives the cluster alerts.
+func receivesClusterAlerts(user *client.User) bool {
+	return user.EmailAlerts && user.ClusterEmailAlerts
+}
+
+// receivesDatabaseAlerts reports whether the user receives the alerts of the
+// database with uid.
+func receivesDatabaseAlerts(user *client.User, uid string) bool {
+	return user.EmailAlerts && slices.Contains(user.BdbsEmailAlerts, uid)
+}
+
+// alertGrants returns the email alerts grants of resource, an object of
+// cluster c, to the users receiving its alerts.
+func alertGrants(ctx context.Context, c *cluster, resource *v2.Resource, receives func(*client.User) bool) ([]*v2.Grant, error) {
+	users, _, err := c.client.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []*v2.Grant
+	for i := range users {
+		if !receives(&users[i]) {
+			continue
+		}
+		userID := &v2.ResourceId{ResourceType: userResourceType.Id, Resource: c.objectID(users[i].UID)}
+		grants = append(grants, grant.NewGrant(resource, emailAlertsEntitlement, userID))
+	}
+
+	return grants, nil
+}
+
+// alertSubscriber returns the user an email alerts entitlement of cluster c
+// is granted to or revoked from.
+func alertSubscriber(ctx context.Context, clusters *clusterSet, c *cluster, principal *v2.ResourceId) (client.User, error) {
+	if principal.ResourceType != userResourceType.Id {
+		return client.User{}, status.Errorf(codes.InvalidArgument, "baton-redis: email alerts can only be granted to users, not %s", principal.ResourceType)
+	}
+
+	userCluster, uid, err := clusters.resolve(principal.Resource)
+	if err != nil {
+		return client.User{}, err
+	}
+	if userCluster != c {
+		return client.User{}, status.Errorf(codes.InvalidArgument, "baton-redis: user %s can only receive the email alerts of its own cluster", principal.Resource)
+	}
+
+	userUID, err := strconv.Atoi(uid)
+	if err != nil {
+		return client.User{}, fmt.Errorf("baton-redis: invalid user id %s: %w", principal.Resource, err)
+	}
+
+	user, _, err := c.client.GetUser(ctx, userUID)
+	if err != nil {
+		return client.User{}, err
+	}
+
+	return user, nil
+}
+
+// setClusterAlerts subscribes the user to the cluster alerts, or unsubscribes
+// it, doing nothing when it already is.
+func setClusterAlerts(ctx context.Context, clusters *clusterSet, c *cluster, principal *v2.ResourceId, subscribe bool) (annotations.Annotations, error) {
+	user, err := alertSubscriber(ctx, clusters, c, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	annos := annotations.Annotations{}
+	switch {
+	case subscribe && receivesClusterAlerts(&user):
+		annos.Update(&v2.GrantAlreadyExists{})
+		return annos, nil
+	case !subscribe && !user.ClusterEmailAlerts:
+		annos.Update(&v2.GrantAlreadyRevoked{})
+		return annos, nil
+	}
+
+	fields := map[string]any{"cluster_email_alerts": subscribe}
+	if subscribe && !user.EmailAlerts {
+		enableAlerts(fields)
+	}
+
+	return annos, updateAlerts(ctx, c, principal, &user, fields)
+}
+
+// setDatabaseAlerts subscribes the user to the alerts of the database with
+// uid, or unsubscribes it, doing nothing when it already is.
+func setDatabaseAlerts(ctx context.Context, clusters *clusterSet, c *cluster, principal *v2.ResourceId, uid string, subscribe bool) (annotations.Annotations, error) {
+	user, err := alertSubscriber(ctx, clusters, c, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	annos := annotations.Annotations{}
+	switch {
+	case subscribe && receivesDatabaseAlerts(&user, uid):
+		annos.Update(&v2.GrantAlreadyExists{})
+		return annos, nil
+	case !subscribe && !slices.Contains(user.BdbsEmailAlerts, uid):
+		annos.Update(&v2.GrantAlreadyRevoked{})
+		return annos, nil
+	}
+
+	databases := []string{}
+	for _, bdbUID := range user.BdbsEmailAlerts {
+		if bdbUID != uid {
+			databases = append(databases, bdbUID)
+		}
+	}
+	fields := map[string]any{}
+	if subscribe {
+		if !user.EmailAlerts {
+			enableAlerts(fields)
+			databases = []string{}
+		}
+		databases = append(databases, uid)
+	}
+	fields["bdbs_email_alerts"] = databases
+
+	return annos, updateAlerts(ctx, c, principal, &user, fields)
+}
+
+// enableAlerts adds to fields, the update subscribing a user whose email
+// alerts are off, turning them on. The subscriptions kept while they were off
+// would start sending alerts along with the new one, fields clears those it
+// doesn't set.
+func enableAlerts(fields map[string]any) {
+	fields["email_alerts"] = true
+	if _, ok := fields["cluster_email_alerts"]; !ok {
+		fields["cluster_email_alerts"] = false
+	}
+	if _, ok := fields["bdbs_email_alerts"]; !ok {
+		fields["bdbs_email_alerts"] = []string{}
+	}
+}
+
+func updateAlerts(ctx context.Context, c *cluster, principal *v2.ResourceId, user *client.User, fields map[string]any) error {
+	if _, _, err := c.client.UpdateUser(ctx, user.UID, fields); err != nil {
+		return err
+	}
+
+	ctxzap.Extract(ctx).Info("baton-redis: changed user email alerts", zap.String("user", principal.Resource), zap.Any("alerts", fields))
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+func TestEmailAlerts_GrantAndRevoke(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.MustAdd(fakeserver.Databases, fakeserver.Object{"name": "db2"})
+	if err := fake.Update(fakeserver.Users, 2, fakeserver.Object{"email_alerts": false, "bdbs_email_alerts": []any{"2"}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	clusters := singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{})))
+	clusterResources, _, _, err := newClusterBuilder(clusters, DefaultSecurityBaseline).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	databaseResources, _, _, err := newDatabaseBuilder(clusters).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	clusterResource, db1 := clusterResources[0], databaseResources[0]
+	viewer := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "2"}}
+
+	clusterEntitlements, _, _, err := newClusterBuilder(clusters, DefaultSecurityBaseline).Entitlements(ctx, clusterResource, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	databaseEntitlements, _, _, err := newDatabaseBuilder(clusters).Entitlements(ctx, db1, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	clusterAlerts, databaseAlerts := clusterEntitlements[0], databaseEntitlements[0]
+
+	user := func() fakeserver.Object {
+		return fake.Get(fakeserver.Users, 2)
+	}
+
+	if _, err := newClusterBuilder(clusters, DefaultSecurityBaseline).Grant(ctx, viewer, clusterAlerts); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if user()["email_alerts"] != true || user()["cluster_email_alerts"] != true {
+		t.Errorf("Expected the user to receive the cluster alerts, got %v", user())
+	}
+	// Turning the email alerts on doesn't subscribe the user to db2 too.
+	if got, _ := user()["bdbs_email_alerts"].([]any); len(got) != 0 {
+		t.Errorf("Expected the user not to receive database alerts, got %v", got)
+	}
+
+	if _, err := newDatabaseBuilder(clusters).Grant(ctx, viewer, databaseAlerts); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got, _ := user()["bdbs_email_alerts"].([]any); !slices.Equal(got, []any{"1"}) {
+		t.Errorf("Expected the user to receive the alerts of db1, got %v", got)
+	}
+
+	annos, err := newDatabaseBuilder(clusters).Grant(ctx, viewer, databaseAlerts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !annos.Contains(&v2.GrantAlreadyExists{}) {
+		t.Errorf("Expected subscribing twice to report GrantAlreadyExists")
+	}
+
+	// Revoking goes through grants, whose entitlement only carries its id.
+	databaseGrant := grant.NewGrant(db1, emailAlertsEntitlement, viewer.Id)
+	if _, err := newDatabaseBuilder(clusters).Revoke(ctx, databaseGrant); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got, _ := user()["bdbs_email_alerts"].([]any); len(got) != 0 {
+		t.Errorf("Expected the user not to receive database alerts, got %v", got)
+	}
+	if user()["email_alerts"] != true || user()["cluster_email_alerts"] != true {
+		t.Errorf("Expected the user to still receive the cluster alerts, got %v", user())
+	}
+
+	clusterGrant := grant.NewGrant(clusterResource, emailAlertsEntitlement, viewer.Id)
+	if _, err := newClusterBuilder(clusters, DefaultSecurityBaseline).Revoke(ctx, clusterGrant); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if user()["cluster_email_alerts"] != false {
+		t.Errorf("Expected the user not to receive the cluster alerts, got %v", user())
+	}
+
+	annos, err = newClusterBuilder(clusters, DefaultSecurityBaseline).Revoke(ctx, clusterGrant)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !annos.Contains(&v2.GrantAlreadyRevoked{}) {
+		t.Errorf("Expected unsubscribing twice to report GrantAlreadyRevoked")
+	}
+
+	allAccess := grant.NewGrant(db1, allAccessEntitlement, viewer.Id)
+	if _, err := newDatabaseBuilder(clusters).Revoke(ctx, allAccess); err == nil {
+		t.Errorf("Expected revoking all access to fail")
+	}
+}
+
+func TestEmailAlerts_GrantKeepsOtherSubscriptions(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.MustAdd(fakeserver.Databases, fakeserver.Object{"name": "db2"})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	clusters := singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{})))
+	databaseResources, _, _, err := newDatabaseBuilder(clusters).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	databaseEntitlements, _, _, err := newDatabaseBuilder(clusters).Entitlements(ctx, databaseResources[0], nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	viewer := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "2"}}
+
+	testCases := []struct {
+		name              string
+		user              fakeserver.Object
+		expectedCluster   bool
+		expectedDatabases []any
+	}{
+		{
+			name:              "alerts on",
+			user:              fakeserver.Object{"email_alerts": true, "cluster_email_alerts": true, "bdbs_email_alerts": []any{"2"}},
+			expectedCluster:   true,
+			expectedDatabases: []any{"2", "1"},
+		},
+		{
+			name:              "alerts off",
+			user:              fakeserver.Object{"email_alerts": false, "cluster_email_alerts": true, "bdbs_email_alerts": []any{"2"}},
+			expectedCluster:   false,
+			expectedDatabases: []any{"1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := fake.Update(fakeserver.Users, 2, tc.user); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if _, err := newDatabaseBuilder(clusters).Grant(ctx, viewer, databaseEntitlements[0]); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			user := fake.Get(fakeserver.Users, 2)
+			if user["email_alerts"] != true || user["cluster_email_alerts"] != tc.expectedCluster {
+				t.Errorf("Expected alerts on and cluster alerts %t, got %v", tc.expectedCluster, user)
+			}
+			if got, _ := user["bdbs_email_alerts"].([]any); !slices.Equal(got, tc.expectedDatabases) {
+				t.Errorf("Expected the alerts of databases %v, got %v", tc.expectedDatabases, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package connector
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-redis/pkg/audit"
+	"github.com/conductorone/baton-redis/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Kinds of events built from audit records.
+const (
+	auditAuthFailed  = "db_auth_failed"
+	auditNewClientIP = "db_new_client_ip"
+)
+
+// auditEvents reads up to pageSize records received after cursor and moves
+// the cursor past them. Records other than failed authentications and first
+// authentications from a client IP are skipped.
+func (d *Connector) auditEvents(cursor *eventCursor, pageSize int) ([]*v2.Event, bool, error) {
+	entries, more := d.audit.Since(cursor.AuditEpoch, cursor.AuditSeq, pageSize)
+
+	// Audit records don't say which cluster sent them, databases can only be
+	// told apart with a single cluster.
+	var c *cluster
+	if len(d.clusters.clusters) == 1 {
+		c = d.clusters.clusters[0]
+	}
+
+	var events []*v2.Event
+	for _, entry := range entries {
+		event, err := auditEvent(c, d.audit.Epoch, entry)
+		if err != nil {
+			return nil, false, err
+		}
+		if event != nil {
+			events = append(events, event)
+		}
+	}
+
+	cursor.AuditEpoch = d.audit.Epoch
+	if len(entries) > 0 {
+		cursor.AuditSeq = entries[len(entries)-1].Seq
+	} else if cursor.AuditSeq > d.audit.Last() {
+		// The cursor comes from a previous run of the receiver.
+		cursor.AuditSeq = d.audit.Last()
+	}
+
+	return events, more, nil
+}
+
+// auditEvent converts an audit record into an event, or returns nil for
+// records that aren't reported. The target is the database, the actor the
+// cluster user the client authenticated as, if any. Both are left out when
+// the cluster c of the record isn't known.
+func auditEvent(c *cluster, epoch string, entry audit.Entry) (*v2.Event, error) {
+	record := entry.Record
+
+	var kind string
+	switch {
+	case record.AuthenticationFailed():
+		kind = auditAuthFailed
+	case entry.FirstSeenIP:
+		kind = auditNewClientIP
+	default:
+		return nil, nil
+	}
+
+	details, err := structpb.NewStruct(map[string]any{
+		"type":        kind,
+		"action":      record.Action,
+		"status":      float64(record.Status),
+		"username":    record.Username,
+		"identity":    record.Identity,
+		"srcip":       record.SourceIP,
+		"srcp":        record.SourcePort,
+		"trgip":       record.TargetIP,
+		"trgp":        record.TargetPort,
+		"hname":       record.Hostname,
+		"bdb_uid":     record.BdbUID,
+		"bdb_name":    record.BdbName,
+		"conn_id":     record.ConnID,
+		"occurred_at": record.Time.Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	annos := annotations.Annotations{}
+	annos.Update(details)
+
+	var target, actor *v2.Resource
+	if c != nil {
+		target = logResource(c, databaseResourceType, client.ObjectUID(record.BdbUID), record.BdbName)
+		if uid, ok := strings.CutPrefix(record.Identity, "user:"); ok {
+			actor = logResource(c, userResourceType, client.ObjectUID(uid), "")
+		}
+	}
+
+	return &v2.Event{
+		Id:         fmt.Sprintf("redis-audit:%s:%d", epoch, entry.Seq),
+		OccurredAt: timestamppb.New(record.Time),
+		Event: &v2.Event_UsageEvent{
+			UsageEvent: &v2.UsageEvent{
+				TargetResource: target,
+				ActorResource:  actor,
+			},
+		},
+		Annotations: annos,
+	}, nil
+}
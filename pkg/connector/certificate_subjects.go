@@ -0,0 +1,250 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"go.uber.org/zap"
+)
+
+// certificateAccessEntitlement is the database entitlement held by the
+// subjects of the client certificates the database accepts.
+const certificateAccessEntitlement = "certificate_access"
+
+// How the subject of a client certificate is matched.
+const (
+	subjectMatchFullSubject = "full_subject"
+	subjectMatchSanCN       = "san_cn"
+	subjectMatchCN          = "cn"
+	subjectMatchAny         = "any"
+)
+
+// anySubject stands for every subject, when the database doesn't check them.
+const anySubject = "*"
+
+// certificateAuthentication reports whether the database authenticates
+// clients by certificate.
+func certificateAuthentication(database *client.Database) bool {
+	return len(database.AuthenticationSSLClientCerts) > 0 && database.EnforceClientAuthentication != "disabled"
+}
+
+// certificateSubject is a subject the database accepts client certificates
+// with, such as "CN=app,O=Acme", and how it is matched.
+type certificateSubject struct {
+	subject string
+	match   string
+}
+
+// certificateSubjects returns the subjects the database accepts client
+// certificates with, in the order it lists them.
+func certificateSubjects(database *client.Database) []certificateSubject {
+	var subjects []certificateSubject
+	add := func(subject, match string) {
+		if subject == "" {
+			return
+		}
+		for _, s := range subjects {
+			if s.subject == subject {
+				return
+			}
+		}
+		subjects = append(subjects, certificateSubject{subject: subject, match: match})
+	}
+
+	switch database.ClientCertSubjectValidationType {
+	case subjectMatchFullSubject:
+		for _, s := range database.AuthorizedSubjects {
+			add(formatSubject(s), subjectMatchFullSubject)
+		}
+	case subjectMatchSanCN:
+		for _, s := range database.AuthorizedSubjects {
+			if s.CN != "" {
+				add("CN="+s.CN, subjectMatchSanCN)
+			}
+		}
+		for _, name := range database.AuthorizedNames {
+			add("CN="+name, subjectMatchSanCN)
+		}
+	default:
+		for _, name := range database.AuthorizedNames {
+			add("CN="+name, subjectMatchCN)
+		}
+	}
+
+	if len(subjects) == 0 {
+		add(anySubject, subjectMatchAny)
+	}
+
+	return subjects
+}
+
+// formatSubject formats a subject the way OpenSSL prints it, from the most
+// specific attribute.
+func formatSubject(s client.CertificateSubject) string {
+	var parts []string
+	add := func(key, value string) {
+		if value != "" {
+			parts = append(parts, key+"="+value)
+		}
+	}
+
+	add("CN", s.CN)
+	for _, ou := range s.OU {
+		add("OU", ou)
+	}
+	add("O", s.O)
+	add("L", s.L)
+	add("ST", s.ST)
+	add("C", s.C)
+
+	return strings.Join(parts, ",")
+}
+
+// trustedCAs parses the certificates the database trusts client certificates
+// from. Certificates that can't be parsed are skipped.
+func trustedCAs(ctx context.Context, database *client.Database) []certificateInfo {
+	var cas []certificateInfo
+	for _, cert := range database.AuthenticationSSLClientCerts {
+		cas = append(cas, parseCertificates(ctx, cert.ClientCert, zap.Int("bdb_uid", database.UID))...)
+	}
+
+	return cas
+}
+
+type certificateSubjectBuilder struct {
+	resourceType *v2.ResourceType
+	clusters     *clusterSet
+	now          func() time.Time
+}
+
+func (o *certificateSubjectBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return certificateSubjectResourceType
+}
+
+// List returns the certificate subjects the parent database accepts clients
+// with, when it authenticates clients by certificate.
+func (o *certificateSubjectBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil || parentResourceID.ResourceType != databaseResourceType.Id {
+		return nil, "", nil, nil
+	}
+
+	c, databaseID, err := o.clusters.resolve(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	database, err := getDatabase(ctx, c, databaseID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if !certificateAuthentication(&database) {
+		return nil, "", nil, nil
+	}
+
+	cas := trustedCAs(ctx, &database)
+	now := o.now()
+
+	var resources []*v2.Resource
+	for _, subject := range certificateSubjects(&database) {
+		subjectResource, err := parseIntoCertificateSubjectResource(c, &database, subject, cas, now, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, subjectResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+// certificateSubjectID returns the resource id of a subject of a database.
+func certificateSubjectID(c *cluster, database *client.Database, subject certificateSubject) string {
+	return c.objectID(fmt.Sprintf("%d:%s", database.UID, subject.subject))
+}
+
+func parseIntoCertificateSubjectResource(
+	c *cluster,
+	database *client.Database,
+	subject certificateSubject,
+	cas []certificateInfo,
+	now time.Time,
+	parent *v2.ResourceId,
+) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"database_id":   database.UID,
+		"database_name": database.Name,
+		"subject":       subject.subject,
+		"match":         subject.match,
+		"tls_mode":      database.TLSMode,
+	}
+
+	var fingerprints, details []interface{}
+	var expiresAt time.Time
+	for _, ca := range cas {
+		fingerprints = append(fingerprints, ca.fingerprint)
+		details = append(details, map[string]interface{}{
+			"fingerprint_sha256": ca.fingerprint,
+			"subject":            ca.subject,
+			"expires_at":         ca.notAfter.UTC().Format(time.RFC3339),
+		})
+		// Certificates are accepted until the last CA expires.
+		if ca.notAfter.After(expiresAt) {
+			expiresAt = ca.notAfter
+		}
+	}
+	profile["trusted_ca_fingerprints"] = fingerprints
+	profile["trusted_cas"] = details
+
+	status := v2.UserTrait_Status_STATUS_ENABLED
+	statusDetails := ""
+	if !expiresAt.IsZero() {
+		profile["trusted_ca_expires_at"] = expiresAt.UTC().Format(time.RFC3339)
+		profile["trusted_ca_expired"] = !now.Before(expiresAt)
+		if !now.Before(expiresAt) {
+			status = v2.UserTrait_Status_STATUS_DISABLED
+			statusDetails = "every trusted CA expired"
+		}
+	}
+
+	userTraits := []resource.UserTraitOption{
+		resource.WithUserProfile(profile),
+		resource.WithDetailedStatus(status, statusDetails),
+		resource.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_SERVICE),
+	}
+
+	displayName := subject.subject
+	if subject.subject == anySubject {
+		displayName = "Any trusted certificate"
+	} else if cn, ok := strings.CutPrefix(strings.Split(subject.subject, ",")[0], "CN="); ok {
+		userTraits = append(userTraits, resource.WithUserLogin(cn))
+	}
+
+	return resource.NewUserResource(
+		fmt.Sprintf("%s on %s", displayName, database.Name),
+		certificateSubjectResourceType,
+		certificateSubjectID(c, database, subject),
+		userTraits,
+		resource.WithParentResourceID(parent),
+	)
+}
+
+func (o *certificateSubjectBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func (o *certificateSubjectBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newCertificateSubjectBuilder(clusters *clusterSet) *certificateSubjectBuilder {
+	return &certificateSubjectBuilder{
+		resourceType: certificateSubjectResourceType,
+		clusters:     clusters,
+		now:          time.Now,
+	}
+}
@@ -0,0 +1,197 @@
+package connector
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+// newTestCA returns a self-signed CA certificate as PEM, and its SHA-256
+// fingerprint.
+func newTestCA(t *testing.T, notAfter time.Time) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	fingerprint := sha256.Sum256(der)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), hex.EncodeToString(fingerprint[:])
+}
+
+func TestCertificateSubjects(t *testing.T) {
+	testCases := []struct {
+		name     string
+		database client.Database
+		expected []certificateSubject
+	}{
+		{
+			name: "full subject",
+			database: client.Database{
+				ClientCertSubjectValidationType: "full_subject",
+				AuthorizedSubjects: []client.CertificateSubject{
+					{CN: "app", OU: []string{"eng", "ops"}, O: "Acme", C: "US"},
+					{CN: "app", OU: []string{"eng", "ops"}, O: "Acme", C: "US"},
+				},
+			},
+			expected: []certificateSubject{{subject: "CN=app,OU=eng,OU=ops,O=Acme,C=US", match: "full_subject"}},
+		},
+		{
+			name: "common name",
+			database: client.Database{
+				ClientCertSubjectValidationType: "san_cn",
+				AuthorizedSubjects:              []client.CertificateSubject{{CN: "app", O: "Acme"}, {O: "Acme"}},
+				AuthorizedNames:                 []string{"worker", "app"},
+			},
+			expected: []certificateSubject{{subject: "CN=app", match: "san_cn"}, {subject: "CN=worker", match: "san_cn"}},
+		},
+		{
+			name:     "authorized names",
+			database: client.Database{AuthorizedNames: []string{"legacy"}},
+			expected: []certificateSubject{{subject: "CN=legacy", match: "cn"}},
+		},
+		{
+			name:     "validation disabled",
+			database: client.Database{ClientCertSubjectValidationType: "disabled"},
+			expected: []certificateSubject{{subject: "*", match: "any"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := certificateSubjects(&tc.database); !slices.Equal(got, tc.expected) {
+				t.Errorf("Expected subjects %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCertificateSubjectBuilder(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	validCA, validFingerprint := newTestCA(t, now.Add(90*24*time.Hour))
+	expiredCA, _ := newTestCA(t, now.Add(-24*time.Hour))
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.MustAdd(fakeserver.Databases, fakeserver.Object{
+		"name":                                "payments",
+		"tls_mode":                            "enabled",
+		"enforce_client_authentication":       "enabled",
+		"authentication_ssl_client_certs":     []any{fakeserver.Object{"client_cert": validCA}},
+		"client_cert_subject_validation_type": "full_subject",
+		"authorized_subjects":                 []any{fakeserver.Object{"CN": "billing", "O": "Acme"}, fakeserver.Object{"CN": "ledger", "O": "Acme"}},
+	})
+	fake.MustAdd(fakeserver.Databases, fakeserver.Object{
+		"name":                            "archive",
+		"tls_mode":                        "enabled",
+		"authentication_ssl_client_certs": []any{fakeserver.Object{"client_cert": expiredCA}},
+	})
+	fake.MustAdd(fakeserver.Databases, fakeserver.Object{
+		"name":                            "optional",
+		"tls_mode":                        "enabled",
+		"enforce_client_authentication":   "disabled",
+		"authentication_ssl_client_certs": []any{fakeserver.Object{"client_cert": validCA}},
+	})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	clusters := singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{})))
+	databases, _, _, err := newDatabaseBuilder(clusters).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	builder := newCertificateSubjectBuilder(clusters)
+	builder.now = func() time.Time { return now }
+
+	subjects := map[string][]*v2.Resource{}
+	grants := map[string][]string{}
+	for _, database := range databases {
+		resources, _, _, err := builder.List(ctx, database.Id, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		subjects[database.DisplayName] = resources
+
+		databaseGrants, _, _, err := newDatabaseBuilder(clusters).Grants(ctx, database, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, g := range databaseGrants {
+			grants[database.DisplayName] = append(grants[database.DisplayName], g.Id)
+		}
+	}
+
+	if len(subjects["db1"]) != 0 || len(subjects["optional"]) != 0 {
+		t.Errorf("Expected no subjects without certificate authentication, got %v %v", subjects["db1"], subjects["optional"])
+	}
+
+	payments := subjects["payments"]
+	if len(payments) != 2 || payments[0].DisplayName != "CN=billing,O=Acme on payments" {
+		t.Fatalf("Expected the authorized subjects of payments, got %v", payments)
+	}
+	userTrait, err := resource.GetUserTrait(payments[0])
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	profile := userTrait.Profile.AsMap()
+	if fingerprints, _ := profile["trusted_ca_fingerprints"].([]any); !slices.Equal(fingerprints, []any{validFingerprint}) {
+		t.Errorf("Expected the trusted CA fingerprint %s, got %v", validFingerprint, profile["trusted_ca_fingerprints"])
+	}
+	if profile["trusted_ca_expires_at"] != "2026-04-01T00:00:00Z" || profile["trusted_ca_expired"] != false {
+		t.Errorf("Expected the trusted CA expiry, got %v", profile)
+	}
+	if userTrait.Status.Status != v2.UserTrait_Status_STATUS_ENABLED || userTrait.Login != "billing" {
+		t.Errorf("Expected an enabled billing principal, got %v", userTrait)
+	}
+	expectedGrants := []string{
+		"database:2:certificate_access:certificate_subject:2:CN=billing,O=Acme",
+		"database:2:certificate_access:certificate_subject:2:CN=ledger,O=Acme",
+	}
+	if !slices.Equal(grants["payments"], expectedGrants) {
+		t.Errorf("Expected grants %v, got %v", expectedGrants, grants["payments"])
+	}
+
+	archive := subjects["archive"]
+	if len(archive) != 1 || archive[0].DisplayName != "Any trusted certificate on archive" {
+		t.Fatalf("Expected any certificate to be accepted by archive, got %v", archive)
+	}
+	userTrait, err = resource.GetUserTrait(archive[0])
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if userTrait.Status.Status != v2.UserTrait_Status_STATUS_DISABLED {
+		t.Errorf("Expected the archive principal to be disabled once its CA expired, got %v", userTrait.Status)
+	}
+}
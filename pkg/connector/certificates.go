@@ -0,0 +1,295 @@
+package connector
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// certificateUsages names the certificates of the cluster, by the name
+// /v1/cluster/certificates returns them under.
+var certificateUsages = map[string]string{
+	"api":              "API",
+	"proxy":            "Proxy",
+	"cm":               "Cluster Manager",
+	"syncer":           "Syncer",
+	"metrics_exporter": "Metrics Exporter",
+}
+
+// trustedClientCAUsage is the usage of the certificates a database trusts
+// client certificates from.
+const trustedClientCAUsage = "trusted_client_ca"
+
+// certificateInfo is a parsed certificate.
+type certificateInfo struct {
+	fingerprint  string
+	subject      string
+	commonName   string
+	issuer       string
+	serialNumber string
+	sans         []string
+	notBefore    time.Time
+	notAfter     time.Time
+}
+
+// parseCertificates parses the certificates of a PEM bundle, in order.
+// Certificates that can't be parsed are logged with fields and skipped.
+func parseCertificates(ctx context.Context, bundle string, fields ...zap.Field) []certificateInfo {
+	l := ctxzap.Extract(ctx)
+
+	var certs []certificateInfo
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		parsed, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			l.Warn("baton-redis: skipping invalid certificate", append(fields, zap.Error(err))...)
+			continue
+		}
+
+		var sans []string
+		sans = append(sans, parsed.DNSNames...)
+		for _, ip := range parsed.IPAddresses {
+			sans = append(sans, ip.String())
+		}
+		sans = append(sans, parsed.EmailAddresses...)
+		for _, uri := range parsed.URIs {
+			sans = append(sans, uri.String())
+		}
+
+		fingerprint := sha256.Sum256(parsed.Raw)
+		certs = append(certs, certificateInfo{
+			fingerprint:  hex.EncodeToString(fingerprint[:]),
+			subject:      parsed.Subject.String(),
+			commonName:   parsed.Subject.CommonName,
+			issuer:       parsed.Issuer.String(),
+			serialNumber: parsed.SerialNumber.Text(16),
+			sans:         sans,
+			notBefore:    parsed.NotBefore,
+			notAfter:     parsed.NotAfter,
+		})
+	}
+
+	return certs
+}
+
+type certificateBuilder struct {
+	resourceType *v2.ResourceType
+	clusters     *clusterSet
+	now          func() time.Time
+}
+
+func (o *certificateBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return certificateResourceType
+}
+
+// List returns the certificates of the parent cluster, or the CAs the parent
+// database trusts client certificates from.
+func (o *certificateBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	var resources []*v2.Resource
+	var err error
+	switch parentResourceID.ResourceType {
+	case clusterResourceType.Id:
+		resources, err = o.listClusterCertificates(ctx, parentResourceID)
+	case databaseResourceType.Id:
+		resources, err = o.listTrustedCAs(ctx, parentResourceID)
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return resources, "", nil, nil
+}
+
+func (o *certificateBuilder) listClusterCertificates(ctx context.Context, parent *v2.ResourceId) ([]*v2.Resource, error) {
+	c, err := o.clusters.forResource(parent.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, _, err := c.client.ListCertificates(ctx)
+	if status.Code(err) == codes.PermissionDenied {
+		ctxzap.Extract(ctx).Warn("baton-redis: unable to read the cluster certificates, skipping them", zap.String("cluster", c.id), zap.Error(err))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(certs))
+	for name := range certs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	now := o.now()
+	var resources []*v2.Resource
+	for _, name := range names {
+		// The certificate comes first, followed by its chain.
+		chain := parseCertificates(ctx, certs[name], zap.String("cluster", c.id), zap.String("certificate", name))
+		if len(chain) == 0 {
+			continue
+		}
+
+		displayName, ok := certificateUsages[name]
+		if !ok {
+			displayName = name
+		}
+		profile := certificateProfile(&chain[0], now)
+		profile["usage"] = name
+		profile["chain_length"] = len(chain)
+
+		certResource, err := newCertificateResource(
+			fmt.Sprintf("%s Certificate", displayName),
+			c.objectID(name),
+			&chain[0],
+			profile,
+			parent,
+			resource.WithSecretIdentityID(parent),
+		)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, certResource)
+	}
+
+	return resources, nil
+}
+
+func (o *certificateBuilder) listTrustedCAs(ctx context.Context, parent *v2.ResourceId) ([]*v2.Resource, error) {
+	c, databaseID, err := o.clusters.resolve(parent.Resource)
+	if err != nil {
+		return nil, err
+	}
+	database, err := getDatabase(ctx, c, databaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := o.now()
+	var resources []*v2.Resource
+	seen := map[string]bool{}
+	for _, ca := range trustedCAs(ctx, &database) {
+		if seen[ca.fingerprint] {
+			continue
+		}
+		seen[ca.fingerprint] = true
+
+		displayName := ca.commonName
+		if displayName == "" {
+			displayName = ca.subject
+		}
+		profile := certificateProfile(&ca, now)
+		profile["usage"] = trustedClientCAUsage
+		profile["database_id"] = database.UID
+		profile["database_name"] = database.Name
+
+		caResource, err := newCertificateResource(
+			fmt.Sprintf("%s on %s", displayName, database.Name),
+			c.objectID(fmt.Sprintf("%d:%s", database.UID, ca.fingerprint)),
+			&ca,
+			profile,
+			parent,
+		)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, caResource)
+	}
+
+	return resources, nil
+}
+
+// certificateProfile returns the profile of the secret resource of cert.
+func certificateProfile(cert *certificateInfo, now time.Time) map[string]interface{} {
+	sans := make([]interface{}, 0, len(cert.sans))
+	for _, san := range cert.sans {
+		sans = append(sans, san)
+	}
+
+	return map[string]interface{}{
+		"subject":            cert.subject,
+		"issuer":             cert.issuer,
+		"serial_number":      cert.serialNumber,
+		"sans":               sans,
+		"fingerprint_sha256": cert.fingerprint,
+		"not_before":         cert.notBefore.UTC().Format(time.RFC3339),
+		"expires_at":         cert.notAfter.UTC().Format(time.RFC3339),
+		"expired":            !now.Before(cert.notAfter),
+	}
+}
+
+func newCertificateResource(
+	name string,
+	id string,
+	cert *certificateInfo,
+	profile map[string]interface{},
+	parent *v2.ResourceId,
+	traitOpts ...resource.SecretTraitOption,
+) (*v2.Resource, error) {
+	profileStruct, err := structpb.NewStruct(profile)
+	if err != nil {
+		return nil, fmt.Errorf("baton-redis: invalid %s profile: %w", certificateResourceType.Id, err)
+	}
+
+	traitOpts = append(traitOpts,
+		resource.WithSecretCreatedAt(cert.notBefore),
+		resource.WithSecretExpiresAt(cert.notAfter),
+		func(t *v2.SecretTrait) error {
+			t.Profile = profileStruct
+			return nil
+		},
+	)
+
+	return resource.NewSecretResource(
+		name,
+		certificateResourceType,
+		id,
+		traitOpts,
+		resource.WithParentResourceID(parent),
+		resource.WithDescription(fmt.Sprintf("Expires %s", cert.notAfter.UTC().Format(time.DateOnly))),
+	)
+}
+
+func (o *certificateBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func (o *certificateBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newCertificateBuilder(clusters *clusterSet) *certificateBuilder {
+	return &certificateBuilder{
+		resourceType: certificateResourceType,
+		clusters:     clusters,
+		now:          time.Now,
+	}
+}
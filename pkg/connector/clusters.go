@@ -0,0 +1,254 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// clusterIDSeparator separates the cluster from the object in the resource id
+// of an object of a scoped cluster, e.g. "east/1".
+const clusterIDSeparator = "/"
+
+// ClusterClient is a cluster to sync, with the client to reach it. Objects of
+// a cluster with an ID are children of the cluster resource and have resource
+// ids prefixed with it. A cluster without one, the one configured through
+// flags, keeps plain uids as ids and its objects at the top level.
+type ClusterClient struct {
+	ID     string
+	Client *client.RedisClient
+}
+
+type cluster struct {
+	id     string
+	client *client.RedisClient
+}
+
+// objectID returns the resource id of the object of the cluster with uid.
+func (c *cluster) objectID(uid interface{}) string {
+	if c.id == "" {
+		return fmt.Sprint(uid)
+	}
+	return c.id + clusterIDSeparator + fmt.Sprint(uid)
+}
+
+// parent returns the id of the resource the objects of the cluster are
+// children of, nil for an unscoped cluster.
+func (c *cluster) parent() *v2.ResourceId {
+	if c.id == "" {
+		return nil
+	}
+	return &v2.ResourceId{ResourceType: clusterResourceType.Id, Resource: c.id}
+}
+
+// clusterSet holds the clusters the connector syncs, in configuration order.
+type clusterSet struct {
+	clusters []*cluster
+}
+
+// singleCluster returns the set of the unscoped cluster reached by c.
+func singleCluster(c *client.RedisClient) *clusterSet {
+	return &clusterSet{clusters: []*cluster{{client: c}}}
+}
+
+// forParent returns the cluster whose objects are listed under parent: the
+// unscoped cluster at the top level, a scoped cluster under its resource.
+func (s *clusterSet) forParent(parent *v2.ResourceId) *cluster {
+	for _, c := range s.clusters {
+		switch {
+		case parent == nil && c.id == "":
+			return c
+		case parent != nil && parent.ResourceType == clusterResourceType.Id && parent.Resource == c.id && c.id != "":
+			return c
+		}
+	}
+	return nil
+}
+
+// resolve returns the cluster of an object resource id and the uid of the
+// object in that cluster.
+func (s *clusterSet) resolve(id string) (*cluster, string, error) {
+	clusterID, uid, scoped := strings.Cut(id, clusterIDSeparator)
+	if !scoped {
+		clusterID, uid = "", id
+	}
+
+	for _, c := range s.clusters {
+		if c.id == clusterID {
+			return c, uid, nil
+		}
+	}
+
+	return nil, "", status.Errorf(codes.NotFound, "baton-redis: no cluster configured for resource %s", id)
+}
+
+// byID returns the cluster with the given id.
+func (s *clusterSet) byID(id string) *cluster {
+	for _, c := range s.clusters {
+		if c.id == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// forResource returns the cluster of a cluster resource id. The resource of
+// the unscoped cluster is named after it rather than identified by its id.
+func (s *clusterSet) forResource(id string) (*cluster, error) {
+	if c := s.byID(id); c != nil {
+		return c, nil
+	}
+	if c := s.byID(""); c != nil {
+		return c, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "baton-redis: no cluster configured for resource %s", id)
+}
+
+type clusterBuilder struct {
+	resourceType *v2.ResourceType
+	clusters     *clusterSet
+	baseline     SecurityBaseline
+}
+
+func (o *clusterBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return clusterResourceType
+}
+
+// List returns a resource per synced cluster, with its security posture
+// checked against the baseline and a summary of its orphaned and unused access
+// objects. Clusters are the parents of their
+// certificates, and scoped ones of their users, roles, Redis ACLs and
+// databases.
+func (o *clusterBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID != nil {
+		return nil, "", nil, nil
+	}
+
+	var resources []*v2.Resource
+	for _, c := range o.clusters.clusters {
+		info, _, err := c.client.GetCluster(ctx)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ldap, err := getLDAP(ctx, c)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		report, err := getAccessReport(ctx, c)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		clusterResource, err := parseIntoClusterResource(c, &info, ldap, report, o.baseline)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, clusterResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+func parseIntoClusterResource(
+	c *cluster,
+	info *client.Cluster,
+	ldap *client.LDAP,
+	report *accessReport,
+	baseline SecurityBaseline,
+) (*v2.Resource, error) {
+	id := c.id
+	if id == "" {
+		id = info.Name
+	}
+	if id == "" {
+		id = clusterResourceType.Id
+	}
+
+	details := map[string]interface{}{
+		"name":         info.Name,
+		"cluster_host": c.client.ClusterHost,
+	}
+	if c.id != "" {
+		details["cluster_id"] = c.id
+	}
+	posture, findings := securityPosture(info, ldap, baseline)
+	securityDetails(details, posture, findings)
+	report.summarize(details, c)
+
+	opts := []resource.ResourceOption{
+		resource.WithDescription(securityDescription(findings)),
+		resource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: certificateResourceType.Id}),
+	}
+	if c.id != "" {
+		opts = append(opts, resource.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: userResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: roleResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: redisACLResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: databaseResourceType.Id},
+		))
+	}
+
+	displayName := info.Name
+	if displayName == "" {
+		displayName = id
+	}
+
+	return newDetailedResource(displayName, clusterResourceType, id, details, opts...)
+}
+
+// Entitlements returns the email alerts entitlement of the cluster.
+func (o *clusterBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{alertsEntitlement(resource, fmt.Sprintf("cluster %s", resource.DisplayName))}, "", nil, nil
+}
+
+// Grants returns the email alerts entitlement granted to the users receiving
+// the cluster alerts.
+func (o *clusterBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	c, err := o.clusters.forResource(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	grants, err := alertGrants(ctx, c, resource, receivesClusterAlerts)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, "", nil, nil
+}
+
+// Grant subscribes a user to the cluster alerts.
+func (o *clusterBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	c, err := o.clusters.forResource(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+	return setClusterAlerts(ctx, o.clusters, c, principal.Id, true)
+}
+
+// Revoke unsubscribes a user from the cluster alerts.
+func (o *clusterBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	c, err := o.clusters.forResource(grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+	return setClusterAlerts(ctx, o.clusters, c, grant.Principal.Id, false)
+}
+
+func newClusterBuilder(clusters *clusterSet, baseline SecurityBaseline) *clusterBuilder {
+	return &clusterBuilder{
+		resourceType: clusterResourceType,
+		clusters:     clusters,
+		baseline:     baseline,
+	}
+}
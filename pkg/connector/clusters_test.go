@@ -0,0 +1,146 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+)
+
+func TestConnector_SyncClusters(t *testing.T) {
+	ctx := context.Background()
+
+	var clusters []ClusterClient
+	for _, id := range []string{"east", "west"} {
+		fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+		fake.SetCluster(fakeserver.Object{"name": id + ".local"})
+		server := fake.Start()
+		t.Cleanup(server.Close)
+
+		clusters = append(clusters, ClusterClient{
+			ID:     id,
+			Client: client.NewClient("admin@example.com", "password", server.URL, ""),
+		})
+	}
+
+	redisConnector, err := NewClusters(ctx, clusters, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	connectorServer, err := connectorbuilder.NewConnector(ctx, redisConnector)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	contents := test.ReadC1Z(ctx, t, test.Sync(ctx, t, connectorServer))
+
+	var resources []string
+	parents := map[string]string{}
+	for _, r := range contents.Resources {
+		key := test.ResourceKey(r.Id)
+		resources = append(resources, key+" "+r.DisplayName)
+		if r.ParentResourceId != nil {
+			parents[key] = test.ResourceKey(r.ParentResourceId)
+		}
+	}
+	expectedResources := []string{
+		"cluster:east east.local",
+		"cluster:west west.local",
+		"database:east/1 db1",
+		"database:west/1 db1",
+		"redis_acl:east/1 Full Access",
+		"redis_acl:east/2 Read Only",
+		"redis_acl:west/1 Full Access",
+		"redis_acl:west/2 Read Only",
+		"role:east/1 Admin",
+		"role:east/2 Viewer",
+		"role:east/3 DB Member",
+		"role:west/1 Admin",
+		"role:west/2 Viewer",
+		"role:west/3 DB Member",
+		"user:east/1 Admin",
+		"user:east/2 Viewer",
+		"user:west/1 Admin",
+		"user:west/2 Viewer",
+	}
+	if !slices.Equal(resources, expectedResources) {
+		t.Errorf("Expected resources %v, got %v", expectedResources, resources)
+	}
+	for key, parent := range map[string]string{
+		"user:east/1":      "cluster:east",
+		"role:west/3":      "cluster:west",
+		"database:west/1":  "cluster:west",
+		"redis_acl:east/2": "cluster:east",
+	} {
+		if parents[key] != parent {
+			t.Errorf("Expected %s to be a child of %s, got %s", key, parent, parents[key])
+		}
+	}
+
+	var grants []string
+	for _, grant := range contents.Grants {
+		grants = append(grants, grant.Id)
+	}
+	expectedGrants := []string{
+		"role:east/1:admin:user:east/1",
+		"role:east/2:cluster_viewer:user:east/2",
+		"role:east/3:db_member:user:east/2",
+		"role:west/1:admin:user:west/1",
+		"role:west/2:cluster_viewer:user:west/2",
+		"role:west/3:db_member:user:west/2",
+	}
+	if !slices.Equal(grants, expectedGrants) {
+		t.Errorf("Expected grants %v, got %v", expectedGrants, grants)
+	}
+}
+
+func TestNewClusters(t *testing.T) {
+	ctx := context.Background()
+	newClient := func() *client.RedisClient {
+		return client.NewClient("admin@example.com", "password", "https://cluster.example.com:9443", "")
+	}
+
+	testCases := []struct {
+		name     string
+		clusters []ClusterClient
+		valid    bool
+	}{
+		{name: "single unscoped cluster", clusters: []ClusterClient{{Client: newClient()}}, valid: true},
+		{name: "scoped clusters", clusters: []ClusterClient{{ID: "east", Client: newClient()}, {ID: "west", Client: newClient()}}, valid: true},
+		{name: "no cluster", valid: false},
+		{name: "unscoped cluster among several", clusters: []ClusterClient{{ID: "east", Client: newClient()}, {Client: newClient()}}, valid: false},
+		{name: "duplicate ids", clusters: []ClusterClient{{ID: "east", Client: newClient()}, {ID: "east", Client: newClient()}}, valid: false},
+		{name: "id with separator", clusters: []ClusterClient{{ID: "us/east", Client: newClient()}}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewClusters(ctx, tc.clusters, false)
+			if tc.valid && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestClusterSet_Resolve(t *testing.T) {
+	set := &clusterSet{clusters: []*cluster{{id: "east"}, {id: "west"}}}
+
+	c, uid, err := set.resolve("west/12")
+	if err != nil || c.id != "west" || uid != "12" {
+		t.Errorf("Expected west and 12, got %v %s %v", c, uid, err)
+	}
+	if _, _, err := set.resolve("12"); err == nil {
+		t.Errorf("Expected an unscoped id not to resolve without an unscoped cluster")
+	}
+	if _, _, err := set.resolve("north/12"); err == nil {
+		t.Errorf("Expected an unknown cluster not to resolve")
+	}
+}
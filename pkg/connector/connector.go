@@ -2,25 +2,84 @@ package connector
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/conductorone/baton-redis/pkg/audit"
 	"github.com/conductorone/baton-redis/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// managementRoles ranks the cluster management roles from least to most
+// privileged.
+var managementRoles = []string{
+	"none",
+	"db_viewer",
+	"cluster_viewer",
+	"db_member",
+	"cluster_member",
+	"user_manager",
+	"admin",
+}
+
 type Connector struct {
-	client *client.RedisClient
+	clusters            *clusterSet
+	provisioningEnabled bool
+	now                 func() time.Time
+	audit               *audit.Receiver
+	baseline            SecurityBaseline
+	thresholds          AccountThresholds
+}
+
+// Option configures optional features of the connector.
+type Option func(*Connector)
+
+// WithAuditReceiver adds the database connection audit records received by r
+// to the events of the connector.
+func WithAuditReceiver(r *audit.Receiver) Option {
+	return func(d *Connector) {
+		d.audit = r
+	}
+}
+
+// WithSecurityBaseline checks the security posture of the clusters against
+// baseline instead of DefaultSecurityBaseline.
+func WithSecurityBaseline(baseline SecurityBaseline) Option {
+	return func(d *Connector) {
+		d.baseline = baseline
+	}
+}
+
+// WithAccountThresholds flags dormant accounts and stale passwords after
+// thresholds instead of DefaultAccountThresholds.
+func WithAccountThresholds(thresholds AccountThresholds) Option {
+	return func(d *Connector) {
+		d.thresholds = thresholds
+	}
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
-		newUserBuilder(d.client),
-		newRoleBuilder(d.client),
+		newClusterBuilder(d.clusters, d.baseline),
+		newUserBuilder(d.clusters, d.thresholds),
+		newRoleBuilder(d.clusters),
+		newRedisACLBuilder(d.clusters),
+		newDatabaseBuilder(d.clusters),
+		newDefaultUserBuilder(d.clusters),
+		newCertificateSubjectBuilder(d.clusters),
+		newCertificateBuilder(d.clusters),
+		newCRDBBuilder(d.clusters),
+		newCRDBInstanceBuilder(d.clusters),
 	}
 }
 
@@ -34,27 +93,202 @@ func (d *Connector) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.R
 func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
 	return &v2.ConnectorMetadata{
 		DisplayName: "Redis Enterprise Connector",
-		Description: "Connector to sync users and roles",
+		Description: "Connector to sync users, roles, databases and Active-Active databases",
 	}, nil
 }
 
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
+//
+// Validate runs at the start of every sync, so it also has the clients drop or
+// refresh whatever they read during the previous one.
 func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
+	for _, c := range d.clusters.clusters {
+		if err := d.validateCluster(ctx, c); err != nil {
+			if c.id != "" {
+				return nil, fmt.Errorf("baton-redis: cluster %s: %w", c.id, err)
+			}
+			return nil, err
+		}
+	}
+
 	return nil, nil
 }
 
-// New returns a new instance of the connector.
-func New(ctx context.Context, redisClient *client.RedisClient) (*Connector, error) {
-	l := ctxzap.Extract(ctx)
+func (d *Connector) validateCluster(ctx context.Context, c *cluster) error {
+	c.client.StartSync(ctx)
+
+	if _, _, err := c.client.GetCluster(ctx); err != nil {
+		return validationError(err, "view the cluster")
+	}
 
-	redisClient, err := client.New(ctx, redisClient)
+	users, _, err := c.client.ListUsers(ctx)
 	if err != nil {
-		l.Error("error creating Redis client", zap.Error(err))
-		return nil, err
+		return validationError(err, "view users")
 	}
 
-	return &Connector{
-		client: redisClient,
-	}, nil
+	roles, _, err := c.client.ListRoles(ctx)
+	if err != nil {
+		return validationError(err, "view roles")
+	}
+
+	user := findUser(users, c.client.Username)
+	if user == nil {
+		// Accounts authenticated through LDAP aren't listed in /v1/users, the
+		// calls above are the only check possible for them.
+		ctxzap.Extract(ctx).Warn(
+			"baton-redis: authenticated user not found in cluster users, skipping management role check",
+			zap.String("username", c.client.Username),
+			zap.String("cluster", c.id),
+		)
+		return nil
+	}
+
+	managementRole := effectiveManagementRole(user, roles)
+	insufficient := managementRole == "none" ||
+		(d.provisioningEnabled && managementRank(managementRole) < managementRank("user_manager"))
+	if unknown := unknownManagementRoles(user, roles); insufficient && len(unknown) > 0 {
+		return fmt.Errorf(
+			"baton-redis: user %s has the unknown management role %s, unable to check it allows syncing or provisioning",
+			c.client.Username,
+			strings.Join(unknown, ", "),
+		)
+	}
+
+	if managementRole == "none" {
+		return fmt.Errorf(
+			"baton-redis: user %s has no management role, syncing requires at least a read-only management role such as cluster_viewer",
+			c.client.Username,
+		)
+	}
+
+	if d.provisioningEnabled && managementRank(managementRole) < managementRank("user_manager") {
+		return fmt.Errorf(
+			"baton-redis: user %s has the %s management role, provisioning requires the user_manager or admin management role",
+			c.client.Username,
+			managementRole,
+		)
+	}
+
+	return nil
+}
+
+// validationError turns an error returned while validating the connector into
+// one that says which credential or permission is missing.
+func validationError(err error, action string) error {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		return fmt.Errorf("baton-redis: invalid username or password: %w", err)
+	case codes.PermissionDenied:
+		return fmt.Errorf("baton-redis: user is not allowed to %s: %w", action, err)
+	default:
+		return fmt.Errorf("baton-redis: unable to %s: %w", action, err)
+	}
+}
+
+func findUser(users []client.User, username string) *client.User {
+	for i, user := range users {
+		if strings.EqualFold(user.Email, username) || strings.EqualFold(user.Name, username) {
+			return &users[i]
+		}
+	}
+	return nil
+}
+
+// effectiveManagementRole returns the most privileged management role the user
+// holds, either directly or through one of its roles.
+func effectiveManagementRole(user *client.User, roles []client.Role) string {
+	managementRole := "none"
+	if managementRank(user.Role) > managementRank(managementRole) {
+		managementRole = user.Role
+	}
+
+	for _, role := range roles {
+		for _, roleUID := range user.RoleUIDs {
+			if role.UID == roleUID && managementRank(role.Management) > managementRank(managementRole) {
+				managementRole = role.Management
+			}
+		}
+	}
+
+	return managementRole
+}
+
+// unknownManagementRoles returns the management roles the user holds, directly
+// or through one of its roles, that aren't listed in managementRoles, such as
+// roles added by a newer cluster version.
+func unknownManagementRoles(user *client.User, roles []client.Role) []string {
+	var unknown []string
+	add := func(managementRole string) {
+		if managementRole != "" && !slices.Contains(managementRoles, managementRole) && !slices.Contains(unknown, managementRole) {
+			unknown = append(unknown, managementRole)
+		}
+	}
+
+	add(user.Role)
+	for _, role := range roles {
+		if slices.Contains(user.RoleUIDs, role.UID) {
+			add(role.Management)
+		}
+	}
+
+	return unknown
+}
+
+func managementRank(managementRole string) int {
+	for rank, role := range managementRoles {
+		if role == managementRole {
+			return rank
+		}
+	}
+	return 0
+}
+
+// New returns a new instance of the connector syncing a single cluster, with
+// plain uids as resource ids. provisioningEnabled raises the management role
+// Validate requires from read-only to user_manager.
+func New(ctx context.Context, redisClient *client.RedisClient, provisioningEnabled bool, opts ...Option) (*Connector, error) {
+	return NewClusters(ctx, []ClusterClient{{Client: redisClient}}, provisioningEnabled, opts...)
+}
+
+// NewClusters returns a new instance of the connector syncing every cluster
+// in clusters. Only a lone cluster may go without an ID.
+func NewClusters(ctx context.Context, clusters []ClusterClient, provisioningEnabled bool, opts ...Option) (*Connector, error) {
+	l := ctxzap.Extract(ctx)
+
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("baton-redis: no cluster to sync")
+	}
+
+	set := &clusterSet{}
+	for _, cc := range clusters {
+		switch {
+		case cc.ID == "" && len(clusters) > 1:
+			return nil, fmt.Errorf("baton-redis: every cluster needs an id when syncing several clusters")
+		case strings.Contains(cc.ID, clusterIDSeparator):
+			return nil, fmt.Errorf("baton-redis: cluster id %s must not contain %q", cc.ID, clusterIDSeparator)
+		case cc.ID != "" && set.byID(cc.ID) != nil:
+			return nil, fmt.Errorf("baton-redis: duplicate cluster id %s", cc.ID)
+		}
+
+		redisClient, err := client.New(ctx, cc.Client)
+		if err != nil {
+			l.Error("error creating Redis client", zap.String("cluster", cc.ID), zap.Error(err))
+			return nil, err
+		}
+		set.clusters = append(set.clusters, &cluster{id: cc.ID, client: redisClient})
+	}
+
+	d := &Connector{
+		clusters:            set,
+		provisioningEnabled: provisioningEnabled,
+		now:                 time.Now,
+		baseline:            DefaultSecurityBaseline,
+		thresholds:          DefaultAccountThresholds,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d, nil
 }
@@ -0,0 +1,143 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test"
+)
+
+const viewerUsers = `[
+  {"uid": 3, "name": "viewer", "email": "viewer@example.com", "role": "cluster_viewer", "role_uids": []},
+  {"uid": 4, "name": "nobody", "email": "nobody@example.com", "role": "none", "role_uids": []},
+  {"uid": 5, "name": "operator", "email": "operator@example.com", "role": "cluster_operator", "role_uids": []}
+]`
+
+// newClusterServer serves the endpoints Validate calls, answering /v1/cluster
+// with clusterStatus and /v1/users with users, or the users mock when empty.
+func newClusterServer(t *testing.T, clusterStatus int, users string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/cluster":
+			w.WriteHeader(clusterStatus)
+			_, _ = w.Write([]byte(`{"name": "cluster.local"}`))
+		case "/v1/users":
+			if users == "" {
+				users = test.ReadFile("usersMock.json")
+			}
+			_, _ = w.Write([]byte(users))
+		case "/v1/roles":
+			_, _ = w.Write([]byte(test.ReadFile("rolesMock.json")))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestConnector_Validate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		username      string
+		users         string
+		clusterStatus int
+		provisioning  bool
+		expectedError string
+	}{
+		{name: "admin can sync", username: "testuser1@redislabs.com", clusterStatus: http.StatusOK},
+		{name: "admin can provision", username: "testuser1@redislabs.com", clusterStatus: http.StatusOK, provisioning: true},
+		{name: "admin role through role uids", username: "Test User 2", clusterStatus: http.StatusOK, provisioning: true},
+		{
+			name:          "wrong password",
+			username:      "testuser1@redislabs.com",
+			clusterStatus: http.StatusUnauthorized,
+			expectedError: "invalid username or password",
+		},
+		{
+			name:          "missing cluster permission",
+			username:      "testuser1@redislabs.com",
+			clusterStatus: http.StatusForbidden,
+			expectedError: "not allowed to view the cluster",
+		},
+		{name: "user not listed", username: "ldap-user", clusterStatus: http.StatusOK, provisioning: true},
+		{name: "viewer can sync", username: "viewer", users: viewerUsers, clusterStatus: http.StatusOK},
+		{
+			name:          "viewer cannot provision",
+			username:      "viewer@example.com",
+			users:         viewerUsers,
+			clusterStatus: http.StatusOK,
+			provisioning:  true,
+			expectedError: "provisioning requires the user_manager or admin management role",
+		},
+		{
+			name:          "unknown management role",
+			username:      "operator",
+			users:         viewerUsers,
+			clusterStatus: http.StatusOK,
+			expectedError: "has the unknown management role cluster_operator",
+		},
+		{
+			name:          "no management role",
+			username:      "nobody",
+			users:         viewerUsers,
+			clusterStatus: http.StatusOK,
+			expectedError: "has no management role",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newClusterServer(t, tc.clusterStatus, tc.users)
+			ctx := context.Background()
+
+			c, err := New(ctx, client.NewClient(tc.username, "password", server.URL, ""), tc.provisioning)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			_, err = c.Validate(ctx)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Fatalf("Expected error containing %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestEffectiveManagementRole(t *testing.T) {
+	roles := []client.Role{
+		{UID: 1, Management: "db_viewer"},
+		{UID: 2, Management: "user_manager"},
+	}
+
+	testCases := []struct {
+		user     client.User
+		expected string
+	}{
+		{user: client.User{}, expected: "none"},
+		{user: client.User{Role: "cluster_viewer"}, expected: "cluster_viewer"},
+		{user: client.User{Role: "db_viewer", RoleUIDs: []int{2}}, expected: "user_manager"},
+		{user: client.User{Role: "admin", RoleUIDs: []int{1, 2}}, expected: "admin"},
+		{user: client.User{Role: "none", RoleUIDs: []int{1}}, expected: "db_viewer"},
+	}
+
+	for _, tc := range testCases {
+		if got := effectiveManagementRole(&tc.user, roles); got != tc.expected {
+			t.Errorf("Expected %s for %+v, got %s", tc.expected, tc.user, got)
+		}
+	}
+}
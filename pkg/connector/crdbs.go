@@ -0,0 +1,417 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// crdbBuilder syncs the Active-Active databases (CRDBs) the synced clusters
+// take part in, with their instances as children. A CRDB is identified by its
+// guid on every cluster, so CRDBs are top level resources listed once however
+// many of their clusters are synced.
+type crdbBuilder struct {
+	resourceType *v2.ResourceType
+	clusters     *clusterSet
+}
+
+func (o *crdbBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return crdbResourceType
+}
+
+// List returns the CRDBs of the cluster. A role granted on one instance gives
+// access to the data everywhere the CRDB replicates, so roles holding
+// different permissions on the instances are reported in the details and the
+// description. Only the instances hosted by synced clusters are compared, a
+// CRDB spanning a single synced cluster never reports any drift.
+func (o *crdbBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID != nil {
+		return nil, "", nil, nil
+	}
+
+	var resources []*v2.Resource
+
+	crdbs, err := listCRDBs(ctx, o.clusters)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, crdb := range crdbs {
+		permissions, err := crdbPermissions(ctx, crdb)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		drift := permissionDrift(permissions)
+		if len(drift) > 0 {
+			ctxzap.Extract(ctx).Warn(
+				"baton-redis: roles have different permissions on the instances of an Active-Active database",
+				zap.String("crdb", crdb.Name),
+				zap.Strings("drift", drift),
+			)
+		}
+
+		crdbResource, err := parseIntoCRDBResource(&crdb.CRDB, crdb.hosts, len(permissions), drift)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, crdbResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+// parseIntoCRDBResource builds the resource of crdb, whose instances hosted by
+// the synced clusters, by instance id, were compared.
+func parseIntoCRDBResource(crdb *client.CRDB, hosts map[int]crdbHost, compared int, drift []string) (*v2.Resource, error) {
+	clusters := make([]interface{}, 0, len(crdb.Instances))
+	for _, instance := range crdb.Instances {
+		clusters = append(clusters, instance.Cluster.Name)
+	}
+
+	driftDetails := make([]interface{}, 0, len(drift))
+	for _, d := range drift {
+		driftDetails = append(driftDetails, d)
+	}
+
+	var notCompared []string
+	notComparedDetails := make([]interface{}, 0, len(crdb.Instances))
+	for _, instance := range crdb.Instances {
+		if _, ok := hosts[instance.ID]; !ok {
+			notCompared = append(notCompared, instance.Cluster.Name)
+			notComparedDetails = append(notComparedDetails, instance.Cluster.Name)
+		}
+	}
+
+	details := map[string]interface{}{
+		"guid":                           crdb.GUID,
+		"name":                           crdb.Name,
+		"encryption":                     crdb.Encryption,
+		"instances":                      len(crdb.Instances),
+		"clusters":                       clusters,
+		"roles_permissions_compared":     compared,
+		"roles_permissions_consistent":   len(drift) == 0,
+		"roles_permissions_drift":        driftDetails,
+		"roles_permissions_not_compared": notComparedDetails,
+	}
+
+	opts := []resource.ResourceOption{
+		resource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: crdbInstanceResourceType.Id}),
+	}
+	var description []string
+	if len(drift) > 0 {
+		description = append(description, "Roles with different permissions across instances: "+strings.Join(drift, "; "))
+	}
+	if len(notCompared) > 0 {
+		description = append(description, "Permissions not compared on the clusters that aren't synced: "+strings.Join(notCompared, ", "))
+	}
+	if len(description) > 0 {
+		opts = append(opts, resource.WithDescription(strings.Join(description, ". ")))
+	}
+
+	return newDetailedResource(crdb.Name, crdbResourceType, crdb.GUID, details, opts...)
+}
+
+func (o *crdbBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func (o *crdbBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newCRDBBuilder(clusters *clusterSet) *crdbBuilder {
+	return &crdbBuilder{
+		resourceType: crdbResourceType,
+		clusters:     clusters,
+	}
+}
+
+// crdbInstanceBuilder syncs the instances of a CRDB, one per participating
+// cluster. The instances hosted by synced clusters are linked to their
+// database.
+type crdbInstanceBuilder struct {
+	resourceType *v2.ResourceType
+	clusters     *clusterSet
+}
+
+func (o *crdbInstanceBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return crdbInstanceResourceType
+}
+
+func (o *crdbInstanceBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	crdbs, err := listCRDBs(ctx, o.clusters)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var resources []*v2.Resource
+	for _, crdb := range crdbs {
+		if crdb.GUID != parentResourceID.Resource {
+			continue
+		}
+
+		permissions, err := crdbPermissions(ctx, crdb)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		for _, instance := range crdb.Instances {
+			instanceResource, err := parseIntoCRDBInstanceResource(crdb, &instance, permissions, parentResourceID)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			resources = append(resources, instanceResource)
+		}
+	}
+
+	return resources, "", nil, nil
+}
+
+func parseIntoCRDBInstanceResource(
+	crdb *syncedCRDB,
+	instance *client.CRDBInstance,
+	permissions []instancePermissions,
+	parentResourceID *v2.ResourceId,
+) (*v2.Resource, error) {
+	details := map[string]interface{}{
+		"instance_id":  instance.ID,
+		"cluster_name": instance.Cluster.Name,
+		"cluster_url":  instance.Cluster.URL,
+		"db_uid":       string(instance.DBUID),
+		"local":        false,
+	}
+
+	if host, ok := crdb.hosts[instance.ID]; ok {
+		bdbUID := host.crdb.LocalDatabaseUID(instance.ID)
+		details["local"] = true
+		details["bdb_uid"] = string(bdbUID)
+		details["database"] = databaseResourceType.Id + ":" + host.cluster.objectID(bdbUID)
+		if host.cluster.id != "" {
+			details["cluster_id"] = host.cluster.id
+		}
+	}
+
+	for _, p := range permissions {
+		if p.instanceID != instance.ID {
+			continue
+		}
+		var rolesPermissions []interface{}
+		for _, role := range p.roles() {
+			rolesPermissions = append(rolesPermissions, role+": "+strings.Join(p.byRole[role], ", "))
+		}
+		details["roles_permissions"] = rolesPermissions
+	}
+
+	name := fmt.Sprintf("%s on %s", crdb.Name, instance.Cluster.Name)
+	id := fmt.Sprintf("%s:%d", crdb.GUID, instance.ID)
+
+	return newDetailedResource(name, crdbInstanceResourceType, id, details, resource.WithParentResourceID(parentResourceID))
+}
+
+func (o *crdbInstanceBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func (o *crdbInstanceBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newCRDBInstanceBuilder(clusters *clusterSet) *crdbInstanceBuilder {
+	return &crdbInstanceBuilder{
+		resourceType: crdbInstanceResourceType,
+		clusters:     clusters,
+	}
+}
+
+// crdbHost is a synced cluster hosting an instance of a CRDB, with the CRDB as
+// that cluster lists it.
+type crdbHost struct {
+	cluster *cluster
+	crdb    client.CRDB
+}
+
+// syncedCRDB is a CRDB as listed by the synced clusters, with the cluster
+// hosting each instance the connector can read, by instance id.
+type syncedCRDB struct {
+	client.CRDB
+	hosts map[int]crdbHost
+}
+
+// listCRDBs lists the CRDBs of every synced cluster, merged by guid.
+func listCRDBs(ctx context.Context, clusters *clusterSet) ([]*syncedCRDB, error) {
+	var res []*syncedCRDB
+	byGUID := make(map[string]*syncedCRDB)
+
+	for _, c := range clusters.clusters {
+		// Note: Redis Enterprise Service API doesn't support pagination.
+		crdbs, _, err := c.client.ListCRDBs(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, crdb := range crdbs {
+			synced, ok := byGUID[crdb.GUID]
+			if !ok {
+				synced = &syncedCRDB{CRDB: crdb, hosts: make(map[int]crdbHost)}
+				byGUID[crdb.GUID] = synced
+				res = append(res, synced)
+			}
+			for _, local := range crdb.LocalDatabases {
+				if _, ok := synced.hosts[local.ID]; !ok {
+					synced.hosts[local.ID] = crdbHost{cluster: c, crdb: crdb}
+				}
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// instancePermissions are the roles_permissions of a CRDB instance, by role
+// name. Roles and Redis ACLs are compared by name since their uids are local
+// to each cluster.
+type instancePermissions struct {
+	instanceID int
+	label      string
+	byRole     map[string][]string
+}
+
+func (p instancePermissions) roles() []string {
+	roles := make([]string, 0, len(p.byRole))
+	for role := range p.byRole {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// crdbPermissions returns the roles_permissions of the instances of crdb whose
+// database the connector can read, that is the instances hosted by the synced
+// clusters. The other instances aren't read: the cluster URLs a CRDB lists are
+// only reachable with the credentials of those clusters, and sending the ones
+// of a synced cluster to them would leak them to whoever controls the CRDB.
+func crdbPermissions(ctx context.Context, crdb *syncedCRDB) ([]instancePermissions, error) {
+	var res []instancePermissions
+
+	for _, instance := range crdb.Instances {
+		host, ok := crdb.hosts[instance.ID]
+		if !ok {
+			continue
+		}
+		bdbUID, err := strconv.Atoi(string(host.crdb.LocalDatabaseUID(instance.ID)))
+		if err != nil {
+			continue
+		}
+
+		databases, _, err := host.cluster.client.ListDatabases(ctx)
+		if err != nil {
+			return nil, err
+		}
+		roles, _, err := host.cluster.client.ListRoles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		acls, _, err := host.cluster.client.ListRedisACLs(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, database := range databases {
+			if database.UID == bdbUID {
+				res = append(res, resolvePermissions(instance, database.RolesPermissions, roles, acls))
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func resolvePermissions(instance client.CRDBInstance, permissions []client.RolePermission, roles []client.Role, acls []client.RedisACL) instancePermissions {
+	roleNames := make(map[int]string, len(roles))
+	for _, role := range roles {
+		roleNames[role.UID] = role.Name
+	}
+	aclNames := make(map[int]string, len(acls))
+	for _, acl := range acls {
+		aclNames[acl.UID] = acl.Name
+	}
+
+	res := instancePermissions{
+		instanceID: instance.ID,
+		label:      fmt.Sprintf("instance %d (%s)", instance.ID, instance.Cluster.Name),
+		byRole:     make(map[string][]string),
+	}
+	for _, permission := range permissions {
+		role, ok := roleNames[permission.RoleUID]
+		if !ok {
+			role = fmt.Sprintf("role #%d", permission.RoleUID)
+		}
+		acl, ok := aclNames[permission.RedisACLUID]
+		if !ok {
+			acl = fmt.Sprintf("ACL #%d", permission.RedisACLUID)
+		}
+		res.byRole[role] = append(res.byRole[role], acl)
+	}
+	for role := range res.byRole {
+		sort.Strings(res.byRole[role])
+	}
+
+	return res
+}
+
+// permissionDrift describes, for every role whose Redis ACLs differ between
+// the instances, the ACLs it holds on each of them.
+func permissionDrift(permissions []instancePermissions) []string {
+	if len(permissions) < 2 {
+		return nil
+	}
+
+	roleSet := make(map[string]bool)
+	for _, p := range permissions {
+		for role := range p.byRole {
+			roleSet[role] = true
+		}
+	}
+	roles := make([]string, 0, len(roleSet))
+	for role := range roleSet {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	var drift []string
+	for _, role := range roles {
+		first := strings.Join(permissions[0].byRole[role], ", ")
+		consistent := true
+		values := make([]string, 0, len(permissions))
+		for _, p := range permissions {
+			acls := strings.Join(p.byRole[role], ", ")
+			if acls != first {
+				consistent = false
+			}
+			if acls == "" {
+				acls = "none"
+			}
+			values = append(values, acls+" on "+p.label)
+		}
+		if !consistent {
+			drift = append(drift, role+": "+strings.Join(values, ", "))
+		}
+	}
+
+	return drift
+}
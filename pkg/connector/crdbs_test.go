@@ -0,0 +1,186 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func resourceDetails(t *testing.T, r *v2.Resource) map[string]any {
+	t.Helper()
+
+	details := &structpb.Struct{}
+	for _, a := range r.Annotations {
+		if a.MessageIs(details) {
+			if err := a.UnmarshalTo(details); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			return details.AsMap()
+		}
+	}
+
+	t.Fatalf("Expected details on %s", r.Id.Resource)
+	return nil
+}
+
+func TestPermissionDrift(t *testing.T) {
+	east := instancePermissions{instanceID: 1, label: "instance 1 (east)", byRole: map[string][]string{
+		"DB Member": {"Read Only"},
+		"Admin":     {"Full Access"},
+	}}
+	west := instancePermissions{instanceID: 2, label: "instance 2 (west)", byRole: map[string][]string{
+		"DB Member": {"Full Access"},
+		"Admin":     {"Full Access"},
+	}}
+	south := instancePermissions{instanceID: 3, label: "instance 3 (south)", byRole: map[string][]string{
+		"Admin": {"Full Access"},
+	}}
+
+	testCases := []struct {
+		name        string
+		permissions []instancePermissions
+		expected    []string
+	}{
+		{name: "single instance", permissions: []instancePermissions{east}},
+		{name: "same permissions", permissions: []instancePermissions{west, west}},
+		{
+			name:        "different ACL",
+			permissions: []instancePermissions{east, west},
+			expected:    []string{"DB Member: Read Only on instance 1 (east), Full Access on instance 2 (west)"},
+		},
+		{
+			name:        "missing role",
+			permissions: []instancePermissions{east, south},
+			expected:    []string{"DB Member: Read Only on instance 1 (east), none on instance 3 (south)"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := permissionDrift(tc.permissions); !slices.Equal(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCRDBBuilders(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.AddCRDB(fakeserver.Object{
+		"guid": "crdb-1",
+		"name": "sessions",
+		"instances": []any{
+			fakeserver.Object{"id": 1, "cluster": fakeserver.Object{"name": "east", "url": "https://east:9443"}, "db_uid": "1"},
+			fakeserver.Object{"id": 2, "cluster": fakeserver.Object{"name": "west", "url": "https://west:9443"}, "db_uid": "7"},
+		},
+		"local_databases": []any{fakeserver.Object{"id": 1, "bdb_uid": "1"}},
+	})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	c := client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+
+	crdbs, _, _, err := newCRDBBuilder(singleCluster(c)).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(crdbs) != 1 {
+		t.Fatalf("Expected 1 CRDB, got %d", len(crdbs))
+	}
+	details := resourceDetails(t, crdbs[0])
+	if details["roles_permissions_compared"] != float64(1) || details["roles_permissions_consistent"] != true {
+		t.Errorf("Expected the local instance to be compared and consistent, got %v", details)
+	}
+	if got, _ := details["roles_permissions_not_compared"].([]any); !slices.Equal(got, []any{"west"}) {
+		t.Errorf("Expected the instance on west not to be compared, got %v", got)
+	}
+
+	instances, _, _, err := newCRDBInstanceBuilder(singleCluster(c)).List(ctx, crdbs[0].Id, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(instances))
+	}
+	for _, instance := range instances {
+		if instance.ParentResourceId.Resource != "crdb-1" {
+			t.Errorf("Expected %s to be a child of the CRDB", instance.Id.Resource)
+		}
+	}
+
+	local := resourceDetails(t, instances[0])
+	if local["local"] != true || local["database"] != "database:1" {
+		t.Errorf("Expected instance 1 to be linked to database 1, got %v", local)
+	}
+	expected := []any{"Admin: Full Access", "DB Member: Read Only"}
+	if got, _ := local["roles_permissions"].([]any); !slices.Equal(got, expected) {
+		t.Errorf("Expected roles permissions %v, got %v", expected, got)
+	}
+
+	remote := resourceDetails(t, instances[1])
+	if remote["local"] != false || remote["db_uid"] != "7" {
+		t.Errorf("Expected instance 2 to be remote, got %v", remote)
+	}
+}
+
+func TestCRDBBuilder_DriftAcrossSyncedClusters(t *testing.T) {
+	ctx := context.Background()
+
+	clusters := &clusterSet{}
+	for i, id := range []string{"east", "west"} {
+		fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+		fake.AddCRDB(fakeserver.Object{
+			"guid": "crdb-1",
+			"name": "sessions",
+			"instances": []any{
+				fakeserver.Object{"id": 1, "cluster": fakeserver.Object{"name": "east", "url": "https://east:9443"}, "db_uid": "1"},
+				fakeserver.Object{"id": 2, "cluster": fakeserver.Object{"name": "west", "url": "https://west:9443"}, "db_uid": "1"},
+			},
+			"local_databases": []any{fakeserver.Object{"id": i + 1, "bdb_uid": "1"}},
+		})
+		if id == "west" {
+			// DB Member holds Full Access instead of Read Only on west.
+			err := fake.Update(fakeserver.Databases, 1, fakeserver.Object{"roles_permissions": []any{
+				fakeserver.Object{"role_uid": 1, "redis_acl_uid": 1},
+				fakeserver.Object{"role_uid": 3, "redis_acl_uid": 1},
+			}})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+		server := fake.Start()
+		t.Cleanup(server.Close)
+
+		c := client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))
+		clusters.clusters = append(clusters.clusters, &cluster{id: id, client: c})
+	}
+
+	crdbs, _, _, err := newCRDBBuilder(clusters).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(crdbs) != 1 {
+		t.Fatalf("Expected 1 CRDB, got %d", len(crdbs))
+	}
+
+	details := resourceDetails(t, crdbs[0])
+	if details["roles_permissions_compared"] != float64(2) || details["roles_permissions_consistent"] != false {
+		t.Errorf("Expected both instances to be compared and inconsistent, got %v", details)
+	}
+	expected := []any{"DB Member: Read Only on instance 1 (east), Full Access on instance 2 (west)"}
+	if got, _ := details["roles_permissions_drift"].([]any); !slices.Equal(got, expected) {
+		t.Errorf("Expected drift %v, got %v", expected, got)
+	}
+	if got, _ := details["roles_permissions_not_compared"].([]any); len(got) != 0 {
+		t.Errorf("Expected every instance to be compared, got %v", got)
+	}
+}
@@ -0,0 +1,248 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type databaseBuilder struct {
+	resourceType *v2.ResourceType
+	clusters     *clusterSet
+}
+
+func (o *databaseBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return databaseResourceType
+}
+
+// List returns the databases of the cluster. Databases have no trait, their
+// settings are attached as a details annotation. Databases clients can connect
+// to as the default user are the parents of a default user account, those
+// authenticating clients by certificate of the subjects they accept.
+func (o *databaseBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	c := o.clusters.forParent(parentResourceID)
+	if c == nil {
+		return nil, "", nil, nil
+	}
+
+	// Note: Redis Enterprise Service API doesn't support pagination.
+	databases, annotation, err := c.client.ListDatabases(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, database := range databases {
+		databaseCopy := database
+		databaseResource, err := parseIntoDatabaseResource(ctx, c, &databaseCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, databaseResource)
+	}
+
+	return resources, "", annotation, nil
+}
+
+func parseIntoDatabaseResource(_ context.Context, c *cluster, database *client.Database) (*v2.Resource, error) {
+	details := map[string]interface{}{
+		"bdb_uid":  database.UID,
+		"name":     database.Name,
+		"type":     database.Type,
+		"status":   database.Status,
+		"version":  database.Version,
+		"port":     database.Port,
+		"tls_mode": database.TLSMode,
+		"crdt":     database.CRDT,
+	}
+	if database.CRDTGUID != "" {
+		details["crdt_guid"] = database.CRDTGUID
+	}
+
+	opts := []resource.ResourceOption{resource.WithParentResourceID(c.parent())}
+	if sharedSecretAccess(database) {
+		details["default_user_access"] = true
+		details["tls_required"] = database.TLSMode == databaseSettingEnabled
+		opts = append(opts, resource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: defaultUserResourceType.Id}))
+	}
+	if certificateAuthentication(database) {
+		details["client_certificate_authentication"] = true
+		details["client_certificate_subjects"] = len(certificateSubjects(database))
+		opts = append(opts, resource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: certificateSubjectResourceType.Id}))
+	}
+	if len(database.AuthenticationSSLClientCerts) > 0 {
+		opts = append(opts, resource.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: certificateResourceType.Id}))
+	}
+
+	return newDetailedResource(database.Name, databaseResourceType, c.objectID(database.UID), details, opts...)
+}
+
+// getDatabase looks the database up in the databases listed for this sync,
+// only asking the cluster for databases created since.
+func getDatabase(ctx context.Context, c *cluster, databaseID string) (client.Database, error) {
+	uid, err := strconv.Atoi(databaseID)
+	if err != nil {
+		return client.Database{}, fmt.Errorf("baton-redis: invalid database id %s: %w", databaseID, err)
+	}
+
+	databases, _, err := c.client.ListDatabases(ctx)
+	if err != nil {
+		return client.Database{}, err
+	}
+	for _, database := range databases {
+		if database.UID == uid {
+			return database, nil
+		}
+	}
+
+	database, _, err := c.client.GetDatabase(ctx, uid)
+	if err != nil {
+		return client.Database{}, err
+	}
+
+	return database, nil
+}
+
+// newDetailedResource builds a resource of a type without traits, with
+// details attached as a structpb.Struct annotation in place of a profile.
+func newDetailedResource(
+	name string,
+	resourceType *v2.ResourceType,
+	objectID interface{},
+	details map[string]interface{},
+	opts ...resource.ResourceOption,
+) (*v2.Resource, error) {
+	detailsStruct, err := structpb.NewStruct(details)
+	if err != nil {
+		return nil, fmt.Errorf("baton-redis: invalid %s details: %w", resourceType.Id, err)
+	}
+
+	return resource.NewResource(name, resourceType, objectID, append(opts, resource.WithAnnotation(detailsStruct))...)
+}
+
+// Entitlements returns the email alerts entitlement of the database, the
+// all-access entitlement of a database clients can connect to as its default
+// user, and the certificate access entitlement of a database authenticating
+// clients by certificate.
+func (o *databaseBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	c, databaseID, err := o.clusters.resolve(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	database, err := getDatabase(ctx, c, databaseID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	entitlements := []*v2.Entitlement{alertsEntitlement(resource, fmt.Sprintf("database %s", database.Name))}
+	if sharedSecretAccess(&database) {
+		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(
+			resource,
+			allAccessEntitlement,
+			entitlement.WithGrantableTo(defaultUserResourceType),
+			entitlement.WithDisplayName(fmt.Sprintf("%s all access", resource.DisplayName)),
+			entitlement.WithDescription(fmt.Sprintf("Every key and command of database %s, through its default user", database.Name)),
+		))
+	}
+	if certificateAuthentication(&database) {
+		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(
+			resource,
+			certificateAccessEntitlement,
+			entitlement.WithGrantableTo(certificateSubjectResourceType),
+			entitlement.WithDisplayName(fmt.Sprintf("%s certificate access", resource.DisplayName)),
+			entitlement.WithDescription(fmt.Sprintf("Connect to database %s with a client certificate signed by a trusted CA", database.Name)),
+		))
+	}
+
+	return entitlements, "", nil, nil
+}
+
+// Grants returns the email alerts grants of the users receiving the database
+// alerts, the all-access grant of the default user of the database and the
+// certificate access grants of the certificate subjects it accepts.
+func (o *databaseBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	c, databaseID, err := o.clusters.resolve(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	database, err := getDatabase(ctx, c, databaseID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	grants, err := alertGrants(ctx, c, resource, func(user *client.User) bool {
+		return receivesDatabaseAlerts(user, databaseID)
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if sharedSecretAccess(&database) {
+		defaultUserID := &v2.ResourceId{ResourceType: defaultUserResourceType.Id, Resource: resource.Id.Resource}
+		grants = append(grants, grant.NewGrant(resource, allAccessEntitlement, defaultUserID))
+	}
+	if certificateAuthentication(&database) {
+		for _, subject := range certificateSubjects(&database) {
+			subjectID := &v2.ResourceId{ResourceType: certificateSubjectResourceType.Id, Resource: certificateSubjectID(c, &database, subject)}
+			grants = append(grants, grant.NewGrant(resource, certificateAccessEntitlement, subjectID))
+		}
+	}
+
+	return grants, "", nil, nil
+}
+
+// Grant subscribes a user to the database alerts. The other entitlements of
+// the database follow from its settings and can't be granted.
+func (o *databaseBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	if slug := entitlementSlug(entitlement); slug != emailAlertsEntitlement {
+		return nil, status.Errorf(codes.InvalidArgument, "baton-redis: the %s entitlement of a database can't be granted", slug)
+	}
+
+	c, databaseID, err := o.clusters.resolve(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+	return setDatabaseAlerts(ctx, o.clusters, c, principal.Id, databaseID, true)
+}
+
+// Revoke unsubscribes a user from the database alerts.
+func (o *databaseBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	if slug := entitlementSlug(grant.Entitlement); slug != emailAlertsEntitlement {
+		return nil, status.Errorf(codes.InvalidArgument, "baton-redis: the %s entitlement of a database can't be revoked", slug)
+	}
+
+	c, databaseID, err := o.clusters.resolve(grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+	return setDatabaseAlerts(ctx, o.clusters, c, grant.Principal.Id, databaseID, false)
+}
+
+// entitlementSlug returns the slug of an entitlement. The entitlement of a
+// grant only carries its id, which ends with the slug.
+func entitlementSlug(e *v2.Entitlement) string {
+	if e.Slug != "" {
+		return e.Slug
+	}
+	prefix := fmt.Sprintf("%s:%s:", e.GetResource().GetId().GetResourceType(), e.GetResource().GetId().GetResource())
+	return strings.TrimPrefix(e.Id, prefix)
+}
+
+func newDatabaseBuilder(clusters *clusterSet) *databaseBuilder {
+	return &databaseBuilder{
+		resourceType: databaseResourceType,
+		clusters:     clusters,
+	}
+}
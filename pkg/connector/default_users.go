@@ -0,0 +1,116 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// allAccessEntitlement is the database entitlement held by the default user:
+// connecting as it gives access to every key and command, whatever the roles
+// of the database.
+const allAccessEntitlement = "all_access"
+
+// databaseSettingEnabled is the value of the tls_mode of a database that only
+// accepts TLS connections, and of its enforce_client_authentication when
+// clients must present a certificate.
+const databaseSettingEnabled = "enabled"
+
+// sharedSecretAccess reports whether anyone holding the database's shared
+// secret, or no secret at all, can connect as its default user.
+func sharedSecretAccess(database *client.Database) bool {
+	return database.DefaultUser || database.AuthenticationRedisPass != ""
+}
+
+type defaultUserBuilder struct {
+	resourceType *v2.ResourceType
+	clusters     *clusterSet
+}
+
+func (o *defaultUserBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return defaultUserResourceType
+}
+
+// List returns the default user account of the parent database, when clients
+// can connect as it.
+func (o *defaultUserBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil || parentResourceID.ResourceType != databaseResourceType.Id {
+		return nil, "", nil, nil
+	}
+
+	c, databaseID, err := o.clusters.resolve(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	database, err := getDatabase(ctx, c, databaseID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if !sharedSecretAccess(&database) {
+		return nil, "", nil, nil
+	}
+
+	defaultUserResource, err := parseIntoDefaultUserResource(c, &database, parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return []*v2.Resource{defaultUserResource}, "", nil, nil
+}
+
+// parseIntoDefaultUserResource builds the synthetic account standing for the
+// default user of a database. It shares the resource id of the database.
+func parseIntoDefaultUserResource(c *cluster, database *client.Database, parent *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"database_id":                 database.UID,
+		"database_name":               database.Name,
+		"shared_secret":               true,
+		"default_user_enabled":        database.DefaultUser,
+		"password_set":                database.AuthenticationRedisPass != "",
+		"passwordless":                database.AuthenticationRedisPass == "",
+		"tls_mode":                    database.TLSMode,
+		"tls_required":                database.TLSMode == databaseSettingEnabled,
+		"client_certificate_required": database.EnforceClientAuthentication == databaseSettingEnabled,
+	}
+
+	status := v2.UserTrait_Status_STATUS_ENABLED
+	statusDetails := "clients can connect with the database password"
+	if database.AuthenticationRedisPass == "" {
+		statusDetails = "clients can connect without a password"
+	}
+
+	userTraits := []resource.UserTraitOption{
+		resource.WithUserProfile(profile),
+		resource.WithDetailedStatus(status, statusDetails),
+		resource.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_SERVICE),
+		resource.WithUserLogin("default"),
+	}
+
+	return resource.NewUserResource(
+		fmt.Sprintf("%s default user", database.Name),
+		defaultUserResourceType,
+		c.objectID(database.UID),
+		userTraits,
+		resource.WithParentResourceID(parent),
+	)
+}
+
+func (o *defaultUserBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func (o *defaultUserBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newDefaultUserBuilder(clusters *clusterSet) *defaultUserBuilder {
+	return &defaultUserBuilder{
+		resourceType: defaultUserResourceType,
+		clusters:     clusters,
+	}
+}
@@ -0,0 +1,114 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+func TestDefaultUserBuilder(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.MustAdd(fakeserver.Databases, fakeserver.Object{"name": "cache", "default_user": true, "tls_mode": "disabled"})
+	fake.MustAdd(fakeserver.Databases, fakeserver.Object{
+		"name":                      "sessions",
+		"default_user":              false,
+		"authentication_redis_pass": "shared-secret",
+		"tls_mode":                  "enabled",
+	})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	clusters := singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{})))
+	databases, _, _, err := newDatabaseBuilder(clusters).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(databases) != 3 {
+		t.Fatalf("Expected 3 databases, got %d", len(databases))
+	}
+
+	expected := map[string]struct {
+		exposed      bool
+		passwordless bool
+		tlsRequired  bool
+	}{
+		"db1":      {exposed: false},
+		"cache":    {exposed: true, passwordless: true, tlsRequired: false},
+		"sessions": {exposed: true, passwordless: false, tlsRequired: true},
+	}
+
+	for _, database := range databases {
+		want := expected[database.DisplayName]
+
+		if got := resourceDetails(t, database)["default_user_access"] == true; got != want.exposed {
+			t.Errorf("Expected %s default user access %v, got %v", database.DisplayName, want.exposed, got)
+		}
+
+		defaultUsers, _, _, err := newDefaultUserBuilder(clusters).List(ctx, database.Id, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var entitlements []*v2.Entitlement
+		databaseEntitlements, _, _, err := newDatabaseBuilder(clusters).Entitlements(ctx, database, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, e := range databaseEntitlements {
+			if e.Slug == allAccessEntitlement {
+				entitlements = append(entitlements, e)
+			}
+		}
+		var grants []*v2.Grant
+		databaseGrants, _, _, err := newDatabaseBuilder(clusters).Grants(ctx, database, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, g := range databaseGrants {
+			if entitlementSlug(g.Entitlement) == allAccessEntitlement {
+				grants = append(grants, g)
+			}
+		}
+
+		if !want.exposed {
+			if len(defaultUsers) != 0 || len(entitlements) != 0 || len(grants) != 0 {
+				t.Errorf("Expected no default user access to %s, got %v %v %v", database.DisplayName, defaultUsers, entitlements, grants)
+			}
+			continue
+		}
+
+		if len(defaultUsers) != 1 {
+			t.Fatalf("Expected a default user for %s, got %d", database.DisplayName, len(defaultUsers))
+		}
+		defaultUser := defaultUsers[0]
+		if defaultUser.Id.ResourceType != defaultUserResourceType.Id || defaultUser.Id.Resource != database.Id.Resource {
+			t.Errorf("Expected the default user to share the database id, got %v", defaultUser.Id)
+		}
+
+		userTrait, err := resource.GetUserTrait(defaultUser)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if userTrait.AccountType != v2.UserTrait_ACCOUNT_TYPE_SERVICE {
+			t.Errorf("Expected a service account, got %v", userTrait.AccountType)
+		}
+		profile := userTrait.Profile.AsMap()
+		if profile["shared_secret"] != true || profile["passwordless"] != want.passwordless || profile["tls_required"] != want.tlsRequired {
+			t.Errorf("Expected %s default user profile %+v, got %v", database.DisplayName, want, profile)
+		}
+
+		if len(entitlements) != 1 {
+			t.Fatalf("Expected the all access entitlement, got %v", entitlements)
+		}
+		if len(grants) != 1 || grants[0].Principal.Id.ResourceType != defaultUserResourceType.Id || grants[0].Principal.Id.Resource != database.Id.Resource {
+			t.Errorf("Expected all access granted to the default user, got %v", grants)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package connector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultEventPageSize = 100
+
+// logCursor is the position of the event feed in the log of a cluster. The log
+// can't be addressed by entry, so the position is the time of the last entry
+// read plus how many entries of that second were read: the log only grows at
+// its end, so these entries are always the first ones returned from that time.
+type logCursor struct {
+	Since  time.Time `json:"since"`
+	Offset int       `json:"offset"`
+}
+
+// eventCursor is the position of the event feed in the log of every cluster,
+// by cluster id, and with an audit receiver the last audit record read.
+type eventCursor struct {
+	Logs       map[string]logCursor `json:"logs,omitempty"`
+	AuditEpoch string               `json:"audit_epoch,omitempty"`
+	AuditSeq   uint64               `json:"audit_seq,omitempty"`
+}
+
+// ListEvents turns the cluster event logs into events: users, roles and
+// databases created, updated or deleted, and failed logins. Other log entries
+// are read and skipped. With an audit receiver, failed database
+// authentications and users authenticating from a new client IP follow.
+func (d *Connector) ListEvents(
+	ctx context.Context,
+	earliestEvent *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	cursor := eventCursor{}
+	if pToken != nil && pToken.Cursor != "" {
+		if err := json.Unmarshal([]byte(pToken.Cursor), &cursor); err != nil {
+			return nil, nil, nil, fmt.Errorf("baton-redis: invalid event cursor: %w", err)
+		}
+	}
+
+	pageSize := defaultEventPageSize
+	if pToken != nil && pToken.Size > 0 {
+		pageSize = pToken.Size
+	}
+
+	next := eventCursor{
+		Logs:       make(map[string]logCursor, len(d.clusters.clusters)),
+		AuditEpoch: cursor.AuditEpoch,
+		AuditSeq:   cursor.AuditSeq,
+	}
+	var events []*v2.Event
+	var annos annotations.Annotations
+	hasMore := false
+
+	for _, c := range d.clusters.clusters {
+		position, ok := cursor.Logs[c.id]
+		if !ok && earliestEvent != nil {
+			position.Since = earliestEvent.AsTime().UTC().Truncate(time.Second)
+		}
+
+		entries, logAnnos, err := c.client.ListLogs(ctx, client.LogQuery{
+			Since:  position.Since,
+			Until:  d.now().UTC().Truncate(time.Second),
+			Limit:  pageSize,
+			Offset: position.Offset,
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		annos = append(annos, logAnnos...)
+
+		logEvents := 0
+		for _, entry := range entries {
+			event, err := d.logEvent(ctx, c, entry)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if event != nil {
+				events = append(events, event)
+				logEvents++
+			}
+		}
+
+		next.Logs[c.id] = advanceCursor(position, entries)
+		hasMore = hasMore || len(entries) == pageSize
+		l.Debug(
+			"baton-redis: read cluster event log",
+			zap.String("cluster", c.id),
+			zap.Int("entries", len(entries)),
+			zap.Int("events", logEvents),
+		)
+	}
+
+	if d.audit != nil {
+		auditEvents, more, err := d.auditEvents(&next, pageSize)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		events = append(events, auditEvents...)
+		hasMore = hasMore || more
+	}
+
+	nextCursor, err := json.Marshal(next)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return events, &pagination.StreamState{
+		Cursor:  string(nextCursor),
+		HasMore: hasMore,
+	}, annos, nil
+}
+
+// advanceCursor moves the cursor past entries, which were read from it.
+func advanceCursor(cursor logCursor, entries []client.LogEntry) logCursor {
+	if len(entries) == 0 {
+		return cursor
+	}
+
+	last := entries[len(entries)-1].Time.UTC().Truncate(time.Second)
+	offset := 0
+	for _, entry := range entries {
+		if entry.Time.UTC().Truncate(time.Second).Equal(last) {
+			offset++
+		}
+	}
+	if last.Equal(cursor.Since) {
+		offset += cursor.Offset
+	}
+
+	return logCursor{Since: last, Offset: offset}
+}
+
+// logEvent converts a log entry into an event, or returns nil for entries that
+// aren't reported.
+func (d *Connector) logEvent(ctx context.Context, c *cluster, entry client.LogEntry) (*v2.Event, error) {
+	var target *v2.Resource
+
+	switch {
+	case strings.HasSuffix(entry.Type, "login_failed"):
+		target = d.loginTarget(ctx, c, entry)
+	case !isChangeLog(entry.Type):
+		return nil, nil
+	case strings.HasPrefix(entry.Type, "user_"):
+		target = logResource(c, userResourceType, entry.UserUID, "")
+	case strings.HasPrefix(entry.Type, "role_"):
+		target = logResource(c, roleResourceType, entry.RoleUID, "")
+	case strings.HasPrefix(entry.Type, "bdb_"):
+		target = logResource(c, databaseResourceType, entry.BdbUID, "")
+	default:
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	details, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Two clusters can log the very same entry.
+	id := sha256.Sum256(append([]byte(c.id+"\n"), raw...))
+	annos := annotations.Annotations{}
+	annos.Update(details)
+
+	return &v2.Event{
+		Id:         "redis-log:" + hex.EncodeToString(id[:16]),
+		OccurredAt: timestamppb.New(entry.Time),
+		Event: &v2.Event_UsageEvent{
+			UsageEvent: &v2.UsageEvent{
+				TargetResource: target,
+				ActorResource:  logResource(c, userResourceType, entry.OriginatorUID, entry.OriginatorUsername),
+			},
+		},
+		Annotations: annos,
+	}, nil
+}
+
+// isChangeLog reports whether a log type records an object being created,
+// updated or deleted.
+func isChangeLog(logType string) bool {
+	for _, suffix := range []string{"_created", "_updated", "_deleted"} {
+		if strings.HasSuffix(logType, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// loginTarget returns the user a failed login was attempted for. Attempts for
+// names that aren't cluster users have no target, the name is kept in the
+// event details.
+func (d *Connector) loginTarget(ctx context.Context, c *cluster, entry client.LogEntry) *v2.Resource {
+	if entry.UserUID != "" {
+		return logResource(c, userResourceType, entry.UserUID, entry.UserName)
+	}
+
+	users, _, err := c.client.ListUsers(ctx)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("baton-redis: unable to resolve user of failed login", zap.Error(err))
+		return nil
+	}
+
+	user := findUser(users, entry.UserName)
+	if user == nil {
+		return nil
+	}
+
+	return logResource(c, userResourceType, client.ObjectUID(fmt.Sprint(user.UID)), user.Name)
+}
+
+// logResource returns the resource of the object of cluster c with uid, nil
+// when the uid is empty.
+func logResource(c *cluster, resourceType *v2.ResourceType, uid client.ObjectUID, displayName string) *v2.Resource {
+	if uid == "" {
+		return nil
+	}
+
+	return &v2.Resource{
+		Id: &v2.ResourceId{
+			ResourceType: resourceType.Id,
+			Resource:     c.objectID(uid),
+		},
+		DisplayName: displayName,
+	}
+}
@@ -0,0 +1,173 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-redis/pkg/audit"
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// readEvents reads the event feed from cursor until it has no more events and
+// returns the events and the cursor to resume from.
+func readEvents(t *testing.T, c *Connector, cursor string, pageSize int) ([]*v2.Event, string) {
+	t.Helper()
+
+	var events []*v2.Event
+	for range 100 {
+		page, state, _, err := c.ListEvents(context.Background(), nil, &pagination.StreamToken{Size: pageSize, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		events = append(events, page...)
+		cursor = state.Cursor
+		if !state.HasMore {
+			return events, cursor
+		}
+	}
+
+	t.Fatalf("Expected the event feed to end")
+	return nil, ""
+}
+
+func eventSummary(event *v2.Event) string {
+	usage := event.GetUsageEvent()
+	summary := ""
+	if target := usage.GetTargetResource(); target != nil {
+		summary = target.Id.ResourceType + ":" + target.Id.Resource
+	}
+	if actor := usage.GetActorResource(); actor != nil {
+		summary += " by " + actor.Id.Resource
+	}
+
+	details := &structpb.Struct{}
+	for _, a := range event.Annotations {
+		if a.MessageIs(details) {
+			_ = a.UnmarshalTo(details)
+		}
+	}
+
+	return details.Fields["type"].GetStringValue() + " " + summary
+}
+
+func TestConnector_ListEvents(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(seconds int) string {
+		return base.Add(time.Duration(seconds) * time.Second).Format(time.RFC3339)
+	}
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	for _, entry := range []fakeserver.Object{
+		{"time": at(0), "type": "user_created", "user_uid": 2, "originator_uid": "1", "originator_username": "Admin"},
+		{"time": at(0), "type": "role_updated", "role_uid": "3", "originator_uid": 1},
+		{"time": at(0), "type": "node_cpu_utilization", "node_uid": 1},
+		{"time": at(1), "type": "bdb_updated", "bdb_uid": 1, "originator_uid": 1},
+		{"time": at(1), "type": "login_failed", "user_name": "viewer@example.com"},
+		{"time": at(1), "type": "login_failed", "user_name": "intruder"},
+	} {
+		fake.AddLog(entry)
+	}
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	now := base.Add(time.Hour)
+	c := &Connector{
+		clusters: singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))),
+		now:      func() time.Time { return now },
+	}
+
+	// Pages of two split the entries logged during the same second.
+	events, cursor := readEvents(t, c, "", 2)
+	expected := []string{
+		"user_created user:2 by 1",
+		"role_updated role:3 by 1",
+		"bdb_updated database:1 by 1",
+		"login_failed user:2",
+		"login_failed ",
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	seen := map[string]bool{}
+	for i, event := range events {
+		if got := eventSummary(event); got != expected[i] {
+			t.Errorf("Event %d: expected %q, got %q", i, expected[i], got)
+		}
+		seen[event.Id] = true
+	}
+
+	// Nothing new, nothing returned.
+	if again, _ := readEvents(t, c, cursor, 2); len(again) != 0 {
+		t.Errorf("Expected no events to be read twice, got %d", len(again))
+	}
+
+	// Entries logged later, including during the last second already read,
+	// are picked up from the cursor exactly once.
+	fake.AddLog(fakeserver.Object{"time": at(1), "type": "user_deleted", "user_uid": 5, "originator_uid": 1})
+	fake.AddLog(fakeserver.Object{"time": at(2), "type": "user_updated", "user_uid": 2, "originator_uid": 1})
+	now = now.Add(time.Minute)
+
+	events, _ = readEvents(t, c, cursor, 2)
+	expected = []string{"user_deleted user:5 by 1", "user_updated user:2 by 1"}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		if got := eventSummary(event); got != expected[i] {
+			t.Errorf("Event %d: expected %q, got %q", i, expected[i], got)
+		}
+		if seen[event.Id] {
+			t.Errorf("Event %s was already read", event.Id)
+		}
+	}
+}
+
+func TestConnector_ListAuditEvents(t *testing.T) {
+	server := fakeserver.NewWithDefaults("admin@example.com", "password").Start()
+	t.Cleanup(server.Close)
+
+	receiver, err := audit.Listen(context.Background(), "tcp://127.0.0.1:0", 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { _ = receiver.Close() })
+
+	c := &Connector{
+		clusters: singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))),
+		now:      time.Now,
+		audit:    receiver,
+	}
+
+	test.ReplayAudit(t, receiver, test.AuditFramesFile)
+
+	events, cursor := readEvents(t, c, "", 2)
+	expected := []string{
+		"db_new_client_ip database:1 by 2",
+		"db_auth_failed database:1",
+		"db_new_client_ip database:1 by 2",
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		if got := eventSummary(event); got != expected[i] {
+			t.Errorf("Event %d: expected %q, got %q", i, expected[i], got)
+		}
+	}
+
+	// Replayed again, the client IPs are known and only the failure is new.
+	test.ReplayAudit(t, receiver, test.AuditFramesFile)
+
+	events, _ = readEvents(t, c, cursor, 2)
+	if len(events) != 1 || eventSummary(events[0]) != "db_auth_failed database:1" {
+		t.Fatalf("Expected a single failed authentication, got %d events", len(events))
+	}
+}
@@ -0,0 +1,215 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Kinds of orphaned or unused access objects.
+const (
+	findingRoleWithoutUsers        = "role_without_users"
+	findingACLWithoutDatabases     = "redis_acl_without_databases"
+	findingUserMissingRoles        = "user_missing_roles"
+	findingLDAPMappingMissingRoles = "ldap_mapping_missing_roles"
+)
+
+// ldapMappingObjectType is the object type LDAP mappings are reported under.
+// They aren't synced as resources.
+const ldapMappingObjectType = "ldap_mapping"
+
+// accessFinding is an access object of the cluster that is orphaned, such as a
+// user holding deleted roles, or unused, such as a role nobody holds.
+type accessFinding struct {
+	kind       string
+	objectType string
+	uid        int
+	name       string
+	// missingRoleUIDs are the deleted roles a user or LDAP mapping still
+	// references.
+	missingRoleUIDs []int
+}
+
+// accessReport lists the orphaned and unused access objects of a cluster.
+type accessReport struct {
+	findings []accessFinding
+}
+
+// buildAccessReport finds the roles held by no user or LDAP mapping, the Redis
+// ACLs no database gives a role, and the users and LDAP mappings referencing
+// deleted roles.
+func buildAccessReport(
+	users []client.User,
+	roles []client.Role,
+	acls []client.RedisACL,
+	databases []client.Database,
+	mappings []client.LDAPMapping,
+) *accessReport {
+	report := &accessReport{}
+
+	existing := make(map[int]bool, len(roles))
+	for _, role := range roles {
+		existing[role.UID] = true
+	}
+	missing := func(roleUIDs []int) []int {
+		var uids []int
+		for _, uid := range roleUIDs {
+			if !existing[uid] && !slices.Contains(uids, uid) {
+				uids = append(uids, uid)
+			}
+		}
+		return uids
+	}
+
+	held := make(map[int]bool)
+	for _, user := range users {
+		for _, uid := range user.RoleUIDs {
+			held[uid] = true
+		}
+	}
+	for _, mapping := range mappings {
+		for _, uid := range mapping.RoleUIDs {
+			held[uid] = true
+		}
+	}
+
+	for _, role := range roles {
+		if !held[role.UID] {
+			report.findings = append(report.findings, accessFinding{
+				kind:       findingRoleWithoutUsers,
+				objectType: roleResourceType.Id,
+				uid:        role.UID,
+				name:       role.Name,
+			})
+		}
+	}
+	for _, acl := range acls {
+		if len(aclDatabases(databases, acl.UID)) == 0 {
+			report.findings = append(report.findings, accessFinding{
+				kind:       findingACLWithoutDatabases,
+				objectType: redisACLResourceType.Id,
+				uid:        acl.UID,
+				name:       acl.Name,
+			})
+		}
+	}
+	for _, user := range users {
+		if uids := missing(user.RoleUIDs); len(uids) > 0 {
+			report.findings = append(report.findings, accessFinding{
+				kind:            findingUserMissingRoles,
+				objectType:      userResourceType.Id,
+				uid:             user.UID,
+				name:            user.Name,
+				missingRoleUIDs: uids,
+			})
+		}
+	}
+	for _, mapping := range mappings {
+		if uids := missing(mapping.RoleUIDs); len(uids) > 0 {
+			report.findings = append(report.findings, accessFinding{
+				kind:            findingLDAPMappingMissingRoles,
+				objectType:      ldapMappingObjectType,
+				uid:             mapping.UID,
+				name:            mapping.Name,
+				missingRoleUIDs: uids,
+			})
+		}
+	}
+
+	return report
+}
+
+// getAccessReport builds the access report of cluster c from the objects read
+// during this sync. LDAP mappings the connector isn't allowed to read are left
+// out.
+func getAccessReport(ctx context.Context, c *cluster) (*accessReport, error) {
+	users, _, err := c.client.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roles, _, err := c.client.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	acls, _, err := c.client.ListRedisACLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	databases, _, err := c.client.ListDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mappings, _, err := c.client.ListLDAPMappings(ctx)
+	if status.Code(err) == codes.PermissionDenied {
+		ctxzap.Extract(ctx).Warn("baton-redis: unable to read the LDAP mappings, skipping them", zap.String("cluster", c.id), zap.Error(err))
+		mappings, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buildAccessReport(users, roles, acls, databases, mappings), nil
+}
+
+// annotate adds the findings about the object of objectType with uid to the
+// profile or details of its resource.
+func (r *accessReport) annotate(fields map[string]interface{}, objectType string, uid int) {
+	var kinds []interface{}
+	for _, f := range r.findings {
+		if f.objectType != objectType || f.uid != uid {
+			continue
+		}
+		kinds = append(kinds, f.kind)
+		if len(f.missingRoleUIDs) > 0 {
+			fields["missing_role_uids"] = intList(f.missingRoleUIDs)
+		}
+	}
+	if len(kinds) > 0 {
+		fields["access_findings"] = kinds
+	}
+}
+
+// summarize adds every finding, and how many there are of each kind, to the
+// details of the cluster resource of c.
+func (r *accessReport) summarize(details map[string]interface{}, c *cluster) {
+	counts := map[string]interface{}{}
+	for _, kind := range []string{findingRoleWithoutUsers, findingACLWithoutDatabases, findingUserMissingRoles, findingLDAPMappingMissingRoles} {
+		counts[kind] = 0
+	}
+	findings := []interface{}{}
+	for _, f := range r.findings {
+		counts[f.kind] = counts[f.kind].(int) + 1
+
+		finding := map[string]interface{}{
+			"kind":        f.kind,
+			"object_type": f.objectType,
+			"name":        f.name,
+		}
+		if f.objectType == ldapMappingObjectType {
+			finding["id"] = strconv.Itoa(f.uid)
+		} else {
+			finding["id"] = c.objectID(f.uid)
+		}
+		if len(f.missingRoleUIDs) > 0 {
+			finding["missing_role_uids"] = intList(f.missingRoleUIDs)
+		}
+		findings = append(findings, finding)
+	}
+
+	details["access_findings"] = findings
+	details["access_finding_counts"] = counts
+}
+
+func intList(values []int) []interface{} {
+	list := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		list = append(list, v)
+	}
+	return list
+}
@@ -0,0 +1,124 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+func TestBuildAccessReport(t *testing.T) {
+	roles := []client.Role{{UID: 1, Name: "Admin"}, {UID: 2, Name: "Unused"}, {UID: 3, Name: "Mapped"}}
+	users := []client.User{{UID: 1, Name: "Admin", RoleUIDs: []int{1}}, {UID: 2, Name: "Stale", RoleUIDs: []int{1, 9, 9}}}
+	acls := []client.RedisACL{{UID: 1, Name: "Full Access"}, {UID: 2, Name: "Unused"}}
+	databases := []client.Database{{UID: 1, Name: "db1", RolesPermissions: []client.RolePermission{{RoleUID: 1, RedisACLUID: 1}}}}
+	mappings := []client.LDAPMapping{{UID: 1, Name: "Ops", RoleUIDs: []int{3, 8}}}
+
+	report := buildAccessReport(users, roles, acls, databases, mappings)
+
+	expected := []accessFinding{
+		{kind: findingRoleWithoutUsers, objectType: roleResourceType.Id, uid: 2, name: "Unused"},
+		{kind: findingACLWithoutDatabases, objectType: redisACLResourceType.Id, uid: 2, name: "Unused"},
+		{kind: findingUserMissingRoles, objectType: userResourceType.Id, uid: 2, name: "Stale", missingRoleUIDs: []int{9}},
+		{kind: findingLDAPMappingMissingRoles, objectType: ldapMappingObjectType, uid: 1, name: "Ops", missingRoleUIDs: []int{8}},
+	}
+	if !slices.EqualFunc(report.findings, expected, func(a, b accessFinding) bool {
+		return a.kind == b.kind && a.objectType == b.objectType && a.uid == b.uid && a.name == b.name &&
+			slices.Equal(a.missingRoleUIDs, b.missingRoleUIDs)
+	}) {
+		t.Errorf("Expected findings %v, got %v", expected, report.findings)
+	}
+}
+
+func TestAccessReportAnnotations(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	unused := fake.MustAdd(fakeserver.Roles, fakeserver.Object{"name": "Unused", "management": "none"})
+	fake.MustAdd(fakeserver.RedisACLs, fakeserver.Object{"name": "Orphan", "acl": "+@read ~orphan:*"})
+	stale := fake.MustAdd(fakeserver.Users, fakeserver.Object{"name": "Stale", "email": "stale@example.com", "role_uids": []any{2, 99}})
+	fake.MustAdd(fakeserver.LDAPMappings, fakeserver.Object{"name": "Ops", "dn": "cn=ops,dc=example,dc=com", "role_uids": []any{98}})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	clusters := singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{})))
+
+	roles, _, _, err := newRoleBuilder(clusters).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, role := range roles {
+		trait, err := resource.GetRoleTrait(role)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		findings := trait.Profile.AsMap()["access_findings"]
+		if role.Id.Resource == strconv.Itoa(unused) && !slices.Equal(findings.([]any), []any{findingRoleWithoutUsers}) {
+			t.Errorf("Expected the Unused role to be reported, got %v", findings)
+		}
+		if role.Id.Resource != strconv.Itoa(unused) && findings != nil {
+			t.Errorf("Expected role %s not to be reported, got %v", role.DisplayName, findings)
+		}
+	}
+
+	users, _, _, err := newUserBuilder(clusters, DefaultAccountThresholds).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, user := range users {
+		trait, err := resource.GetUserTrait(user)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		profile := trait.Profile.AsMap()
+		if user.Id.Resource != strconv.Itoa(stale) {
+			if profile["access_findings"] != nil {
+				t.Errorf("Expected user %s not to be reported, got %v", user.DisplayName, profile["access_findings"])
+			}
+			continue
+		}
+		if !slices.Equal(profile["access_findings"].([]any), []any{findingUserMissingRoles}) || !slices.Equal(profile["missing_role_uids"].([]any), []any{float64(99)}) {
+			t.Errorf("Expected the Stale user to be reported with role 99, got %v", profile)
+		}
+	}
+
+	acls, _, _, err := newRedisACLBuilder(clusters).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var reported []string
+	for _, acl := range acls {
+		if resourceDetails(t, acl)["access_findings"] != nil {
+			reported = append(reported, acl.DisplayName)
+		}
+	}
+	if !slices.Equal(reported, []string{"Orphan"}) {
+		t.Errorf("Expected only the Orphan ACL to be reported, got %v", reported)
+	}
+
+	clusterResources, _, _, err := newClusterBuilder(clusters, DefaultSecurityBaseline).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	details := resourceDetails(t, clusterResources[0])
+	counts := details["access_finding_counts"].(map[string]any)
+	for _, kind := range []string{findingRoleWithoutUsers, findingACLWithoutDatabases, findingUserMissingRoles, findingLDAPMappingMissingRoles} {
+		if counts[kind] != float64(1) {
+			t.Errorf("Expected 1 %s finding, got %v", kind, counts[kind])
+		}
+	}
+	var ids []string
+	for _, finding := range details["access_findings"].([]any) {
+		ids = append(ids, finding.(map[string]any)["id"].(string))
+	}
+	expected := []string{"4", "3", "3", "1"}
+	if !slices.Equal(ids, expected) {
+		t.Errorf("Expected findings about %v, got %v", expected, ids)
+	}
+}
@@ -0,0 +1,341 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// aclCategories are the command categories Redis ACL rules can allow or deny,
+// as in +@read, including those of the modules Redis Enterprise ships.
+var aclCategories = []string{
+	"admin", "all", "bitmap", "blocking", "connection", "dangerous", "fast", "geo", "hash", "hyperloglog",
+	"keyspace", "list", "pubsub", "read", "scripting", "set", "slow", "sortedset", "stream", "string",
+	"transaction", "write",
+	"search", "json", "timeseries", "bloom", "cuckoo", "topk", "cms", "tdigest",
+}
+
+// aclKeywords are the Redis ACL rules that take no argument.
+var aclKeywords = []string{"allkeys", "resetkeys", "allcommands", "nocommands", "allchannels", "resetchannels"}
+
+// aclCommand matches a command, or a command and its subcommand, as in
+// +client|list.
+var aclCommand = regexp.MustCompile(`^[a-z][a-z0-9_.-]*(\|[a-z0-9_.-]+)?$`)
+
+// validateACL checks the rules of a Redis ACL, such as "+@read ~cache:*",
+// before they are sent to the cluster. Selectors, as in "+@read (+set ~a*)",
+// hold rules of their own.
+func validateACL(acl string) error {
+	rules, err := splitACL(acl)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return fmt.Errorf("baton-redis: empty Redis ACL")
+	}
+
+	for _, rule := range rules {
+		if selector, ok := strings.CutPrefix(rule, "("); ok {
+			selector = strings.TrimSuffix(selector, ")")
+			if strings.ContainsAny(selector, "()") {
+				return fmt.Errorf("baton-redis: nested selector %q in Redis ACL", rule)
+			}
+			if strings.TrimSpace(selector) == "" {
+				return fmt.Errorf("baton-redis: empty selector in Redis ACL")
+			}
+			for _, selectorRule := range strings.Fields(selector) {
+				if err := validateACLRule(selectorRule); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := validateACLRule(rule); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitACL splits the rules of a Redis ACL, keeping every selector as a single
+// rule.
+func splitACL(acl string) ([]string, error) {
+	var rules, selector []string
+	for _, field := range strings.Fields(acl) {
+		switch {
+		case selector != nil:
+			selector = append(selector, field)
+		case strings.HasPrefix(field, "("):
+			selector = []string{field}
+		default:
+			rules = append(rules, field)
+			continue
+		}
+
+		if strings.HasSuffix(field, ")") {
+			rules = append(rules, strings.Join(selector, " "))
+			selector = nil
+		}
+	}
+	if selector != nil {
+		return nil, fmt.Errorf("baton-redis: unterminated selector %q in Redis ACL", strings.Join(selector, " "))
+	}
+
+	return rules, nil
+}
+
+func validateACLRule(rule string) error {
+	lower := strings.ToLower(rule)
+	if slices.Contains(aclKeywords, lower) {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(rule, "+@"), strings.HasPrefix(rule, "-@"):
+		if !slices.Contains(aclCategories, lower[2:]) {
+			return fmt.Errorf("baton-redis: unknown command category %q in Redis ACL", rule)
+		}
+	case strings.HasPrefix(rule, "+"), strings.HasPrefix(rule, "-"):
+		if !aclCommand.MatchString(lower[1:]) {
+			return fmt.Errorf("baton-redis: invalid command %q in Redis ACL", rule)
+		}
+	case strings.HasPrefix(rule, "~"), strings.HasPrefix(rule, "&"):
+		if len(rule) == 1 {
+			return fmt.Errorf("baton-redis: empty pattern %q in Redis ACL", rule)
+		}
+	case strings.HasPrefix(rule, "%"):
+		permissions, pattern, ok := strings.Cut(rule[1:], "~")
+		if !ok || pattern == "" || !slices.Contains([]string{"R", "W", "RW"}, strings.ToUpper(permissions)) {
+			return fmt.Errorf("baton-redis: invalid key permissions %q in Redis ACL", rule)
+		}
+	default:
+		return fmt.Errorf("baton-redis: unsupported rule %q in Redis ACL", rule)
+	}
+
+	return nil
+}
+
+// aclDatabases returns the names of the databases giving a role the Redis ACL
+// with aclUID.
+func aclDatabases(databases []client.Database, aclUID int) []string {
+	var names []string
+	for _, database := range databases {
+		for _, permission := range database.RolesPermissions {
+			if permission.RedisACLUID == aclUID {
+				names = append(names, database.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+type redisACLBuilder struct {
+	resourceType *v2.ResourceType
+	clusters     *clusterSet
+}
+
+func (o *redisACLBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return redisACLResourceType
+}
+
+func (o *redisACLBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	c := o.clusters.forParent(parentResourceID)
+	if c == nil {
+		return nil, "", nil, nil
+	}
+
+	acls, annotation, err := c.client.ListRedisACLs(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	databases, _, err := c.client.ListDatabases(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var resources []*v2.Resource
+	for i := range acls {
+		aclResource, err := parseIntoRedisACLResource(c, &acls[i], databases)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, aclResource)
+	}
+
+	return resources, "", annotation, nil
+}
+
+func parseIntoRedisACLResource(c *cluster, acl *client.RedisACL, databases []client.Database) (*v2.Resource, error) {
+	var referencedBy []interface{}
+	for _, name := range aclDatabases(databases, acl.UID) {
+		referencedBy = append(referencedBy, name)
+	}
+
+	details := map[string]interface{}{
+		"redis_acl_id": acl.UID,
+		"name":         acl.Name,
+		"acl":          acl.ACL,
+		"databases":    referencedBy,
+	}
+	if len(referencedBy) == 0 {
+		details["access_findings"] = []interface{}{findingACLWithoutDatabases}
+	}
+
+	return newDetailedResource(
+		acl.Name,
+		redisACLResourceType,
+		c.objectID(acl.UID),
+		details,
+		resource.WithParentResourceID(c.parent()),
+		resource.WithDescription(acl.ACL),
+	)
+}
+
+func (o *redisACLBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func (o *redisACLBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Create creates the Redis ACL named after the display name of the resource,
+// with the rules of its acl detail. An existing ACL with the same name is
+// updated instead, so applying the same definition to every cluster converges.
+func (o *redisACLBuilder) Create(ctx context.Context, r *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	c := o.clusters.forParent(r.ParentResourceId)
+	if c == nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "baton-redis: no cluster configured for Redis ACL parent %v", r.ParentResourceId)
+	}
+
+	name := strings.TrimSpace(r.DisplayName)
+	if name == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "baton-redis: a Redis ACL needs a name")
+	}
+	rules, err := aclRules(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validateACL(rules); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// The ACLs are read again, one created since they were cached would be
+	// created twice.
+	acls, _, err := c.client.FetchRedisACLs(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var acl client.RedisACL
+	existing := slices.IndexFunc(acls, func(a client.RedisACL) bool { return a.Name == name })
+	switch {
+	case existing < 0:
+		acl, _, err = c.client.CreateRedisACL(ctx, name, rules)
+		if err != nil {
+			return nil, nil, err
+		}
+		l.Info("baton-redis: created Redis ACL", zap.String("cluster", c.id), zap.String("name", name), zap.Int("uid", acl.UID))
+	case acls[existing].ACL != rules:
+		acl, _, err = c.client.UpdateRedisACL(ctx, acls[existing].UID, rules)
+		if err != nil {
+			return nil, nil, err
+		}
+		l.Info("baton-redis: updated Redis ACL", zap.String("cluster", c.id), zap.String("name", name), zap.Int("uid", acl.UID))
+	default:
+		acl = acls[existing]
+	}
+
+	databases, _, err := c.client.FetchDatabases(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	created, err := parseIntoRedisACLResource(c, &acl, databases)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return created, nil, nil
+}
+
+// aclRules returns the rules of the Redis ACL resource to create, from its acl
+// detail.
+func aclRules(r *v2.Resource) (string, error) {
+	details := &structpb.Struct{}
+	annos := annotations.Annotations(r.Annotations)
+	ok, err := annos.Pick(details)
+	if err != nil {
+		return "", err
+	}
+	if !ok || details.Fields["acl"].GetStringValue() == "" {
+		return "", status.Error(codes.InvalidArgument, "baton-redis: a Redis ACL needs its rules in the acl detail")
+	}
+
+	return strings.TrimSpace(details.Fields["acl"].GetStringValue()), nil
+}
+
+// Delete deletes a Redis ACL no database gives a role anymore.
+func (o *redisACLBuilder) Delete(ctx context.Context, resourceID *v2.ResourceId) (annotations.Annotations, error) {
+	c, uid, err := o.clusters.resolve(resourceID.Resource)
+	if err != nil {
+		return nil, err
+	}
+	aclUID, err := strconv.Atoi(uid)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "baton-redis: invalid Redis ACL id %s", resourceID.Resource)
+	}
+
+	// The databases are read again, one given the ACL since they were cached
+	// would lose it.
+	databases, _, err := c.client.FetchDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if names := aclDatabases(databases, aclUID); len(names) > 0 {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"baton-redis: Redis ACL %s is still given to roles on databases %s",
+			resourceID.Resource,
+			strings.Join(names, ", "),
+		)
+	}
+
+	annos, err := c.client.DeleteRedisACL(ctx, aclUID)
+	if status.Code(err) == codes.NotFound {
+		ctxzap.Extract(ctx).Info("baton-redis: Redis ACL already deleted", zap.String("redis_acl", resourceID.Resource))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctxzap.Extract(ctx).Info("baton-redis: deleted Redis ACL", zap.String("redis_acl", resourceID.Resource))
+
+	return annos, nil
+}
+
+func newRedisACLBuilder(clusters *clusterSet) *redisACLBuilder {
+	return &redisACLBuilder{
+		resourceType: redisACLResourceType,
+		clusters:     clusters,
+	}
+}
@@ -0,0 +1,163 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestValidateACL(t *testing.T) {
+	testCases := []struct {
+		acl   string
+		valid bool
+	}{
+		{acl: "+@all ~*", valid: true},
+		{acl: "+@read -@dangerous ~cache:* &notifications:*", valid: true},
+		{acl: "+get +client|list %R~reports:* %RW~sessions:*", valid: true},
+		{acl: "+@read ~* (+set ~tmp:*)", valid: true},
+		{acl: "allcommands allkeys resetchannels", valid: true},
+		{acl: "+@search +@json +@timeseries +@bloom +@cuckoo +@topk +@cms +@tdigest ~*", valid: true},
+		{acl: "", valid: false},
+		{acl: "+@reads ~*", valid: false},
+		{acl: "+get ~", valid: false},
+		{acl: "%X~keys:*", valid: false},
+		{acl: "on >password +@all ~*", valid: false},
+		{acl: "+@read (+set ~tmp:*", valid: false},
+		{acl: "+@read (+set (~tmp:*))", valid: false},
+		{acl: "+get! ~*", valid: false},
+	}
+
+	for _, tc := range testCases {
+		err := validateACL(tc.acl)
+		if tc.valid && err != nil {
+			t.Errorf("Expected %q to be valid, got %v", tc.acl, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("Expected %q to be invalid", tc.acl)
+		}
+	}
+}
+
+// newACLResource returns the Redis ACL resource requested for creation.
+func newACLResource(t *testing.T, name, acl string) *v2.Resource {
+	t.Helper()
+
+	details, err := structpb.NewStruct(map[string]interface{}{"acl": acl})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	annotation, err := anypb.New(details)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	return &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: redisACLResourceType.Id},
+		DisplayName: name,
+		Annotations: []*anypb.Any{annotation},
+	}
+}
+
+func TestRedisACLBuilder_CreateAndDelete(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	clusters := singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{})))
+	builder := newRedisACLBuilder(clusters)
+
+	created, _, err := builder.Create(ctx, newACLResource(t, "Reports", "+@read ~reports:*"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.Id.Resource != "3" || created.DisplayName != "Reports" {
+		t.Fatalf("Expected the Reports ACL to be created, got %v", created)
+	}
+	if acl := fake.Get(fakeserver.RedisACLs, 3); acl["acl"] != "+@read ~reports:*" {
+		t.Errorf("Expected the ACL rules to be stored, got %v", acl)
+	}
+
+	updated, _, err := builder.Create(ctx, newACLResource(t, "Reports", "+@read +@write ~reports:*"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if updated.Id.Resource != "3" || fake.Get(fakeserver.RedisACLs, 3)["acl"] != "+@read +@write ~reports:*" {
+		t.Errorf("Expected creating an existing ACL to update it, got %v", updated)
+	}
+	if acls := fake.List(fakeserver.RedisACLs); len(acls) != 3 {
+		t.Errorf("Expected 3 ACLs, got %v", acls)
+	}
+
+	if _, _, err := builder.Create(ctx, newACLResource(t, "Broken", "+@everything ~*")); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected an invalid ACL to be refused, got %v", err)
+	}
+	if acls := fake.List(fakeserver.RedisACLs); len(acls) != 3 {
+		t.Errorf("Expected an invalid ACL not to be sent, got %v", acls)
+	}
+
+	_, err = builder.Delete(ctx, &v2.ResourceId{ResourceType: redisACLResourceType.Id, Resource: "2"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected deleting an ACL db1 gives a role to be refused, got %v", err)
+	}
+	if fake.Get(fakeserver.RedisACLs, 2) == nil {
+		t.Errorf("Expected the Read Only ACL to be kept")
+	}
+
+	if _, err := builder.Delete(ctx, created.Id); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if fake.Get(fakeserver.RedisACLs, 3) != nil {
+		t.Errorf("Expected the Reports ACL to be deleted")
+	}
+	if _, err := builder.Delete(ctx, created.Id); err != nil {
+		t.Errorf("Expected deleting a deleted ACL to succeed, got %v", err)
+	}
+}
+
+func TestRedisACLBuilder_ChecksCurrentACLs(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	clusters := singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{})))
+	builder := newRedisACLBuilder(clusters)
+
+	// The sync caches the ACLs and databases before they change.
+	if _, _, _, err := builder.List(ctx, nil, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	reports := fake.MustAdd(fakeserver.RedisACLs, fakeserver.Object{"name": "Reports", "acl": "+@read ~reports:*"})
+	err := fake.Update(fakeserver.Databases, 1, fakeserver.Object{"roles_permissions": []any{
+		fakeserver.Object{"role_uid": 3, "redis_acl_uid": reports},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	created, _, err := builder.Create(ctx, newACLResource(t, "Reports", "+@read +@write ~reports:*"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.Id.Resource != strconv.Itoa(reports) || len(fake.List(fakeserver.RedisACLs)) != 3 {
+		t.Errorf("Expected the Reports ACL created since the sync to be updated, got %v", fake.List(fakeserver.RedisACLs))
+	}
+
+	_, err = builder.Delete(ctx, &v2.ResourceId{ResourceType: redisACLResourceType.Id, Resource: strconv.Itoa(reports)})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected deleting an ACL db1 gives a role since the sync to be refused, got %v", err)
+	}
+}
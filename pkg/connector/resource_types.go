@@ -4,6 +4,13 @@ import (
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 )
 
+// The cluster resource type is for the Redis Enterprise clusters synced by the
+// connector.
+var clusterResourceType = &v2.ResourceType{
+	Id:          "cluster",
+	DisplayName: "Cluster",
+}
+
 // The user resource type is for all user objects from the database.
 var userResourceType = &v2.ResourceType{
 	Id:          "user",
@@ -16,3 +23,55 @@ var roleResourceType = &v2.ResourceType{
 	DisplayName: "Role",
 	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_ROLE},
 }
+
+// The Redis ACL resource type is for the named sets of Redis ACL rules roles
+// are given on databases.
+var redisACLResourceType = &v2.ResourceType{
+	Id:          "redis_acl",
+	DisplayName: "Redis ACL",
+}
+
+// The database resource type is for the databases (BDBs) of the cluster.
+var databaseResourceType = &v2.ResourceType{
+	Id:          "database",
+	DisplayName: "Database",
+}
+
+// The default user resource type is for the default user of a database,
+// reachable by anyone holding its shared password, or by anyone when it has
+// none.
+var defaultUserResourceType = &v2.ResourceType{
+	Id:          "default_user",
+	DisplayName: "Database Default User",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+}
+
+// The certificate subject resource type is for the subjects of the client
+// certificates a database accepts.
+var certificateSubjectResourceType = &v2.ResourceType{
+	Id:          "certificate_subject",
+	DisplayName: "Client Certificate Subject",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+}
+
+// The certificate resource type is for the certificates of the cluster, and the
+// CAs databases trust client certificates from.
+var certificateResourceType = &v2.ResourceType{
+	Id:          "certificate",
+	DisplayName: "Certificate",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_SECRET},
+}
+
+// The CRDB resource type is for Active-Active databases, which replicate
+// across several clusters.
+var crdbResourceType = &v2.ResourceType{
+	Id:          "crdb",
+	DisplayName: "Active-Active Database",
+}
+
+// The CRDB instance resource type is for the database of a CRDB on one of its
+// clusters.
+var crdbInstanceResourceType = &v2.ResourceType{
+	Id:          "crdb_instance",
+	DisplayName: "Active-Active Database Instance",
+}
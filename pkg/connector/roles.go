@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"strconv"
-	"sync"
 
 	"github.com/conductorone/baton-redis/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -17,29 +16,34 @@ import (
 
 type roleBuilder struct {
 	resourceType *v2.ResourceType
-	client       *client.RedisClient
-	users        []client.User
-	usersMutex   sync.RWMutex
-	roles        map[int]client.Role
-	rolesMutex   sync.RWMutex
+	clusters     *clusterSet
 }
 
 func (o *roleBuilder) ResourceType(_ context.Context) *v2.ResourceType {
 	return roleResourceType
 }
 
-func (o *roleBuilder) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+func (o *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
 	var resources []*v2.Resource
 
+	c := o.clusters.forParent(parentResourceID)
+	if c == nil {
+		return nil, "", nil, nil
+	}
+
 	// Note: Redis Enterprise Service API doesn't support pagination.
-	roles, annotation, err := o.client.ListRoles(ctx)
+	roles, annotation, err := c.client.ListRoles(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	report, err := getAccessReport(ctx, c)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	for _, role := range roles {
 		roleCopy := role
-		roleResource, err := parseIntoRoleResource(ctx, &roleCopy, nil)
+		roleResource, err := parseIntoRoleResource(ctx, c, &roleCopy, report)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -49,12 +53,18 @@ func (o *roleBuilder) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.
 	return resources, "", annotation, nil
 }
 
-func parseIntoRoleResource(_ context.Context, role *client.Role, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+// parseIntoRoleResource builds the resource of a role of cluster c, annotated
+// with the findings of report about it.
+func parseIntoRoleResource(_ context.Context, c *cluster, role *client.Role, report *accessReport) (*v2.Resource, error) {
 	profile := map[string]interface{}{
 		"role_id":         role.UID,
 		"name":            role.Name,
 		"management_role": role.Management,
 	}
+	if role.AccountID != 0 {
+		profile["account_id"] = role.AccountID
+	}
+	report.annotate(profile, roleResourceType.Id, role.UID)
 
 	roleTraits := []resource.RoleTraitOption{
 		resource.WithRoleProfile(profile),
@@ -65,9 +75,9 @@ func parseIntoRoleResource(_ context.Context, role *client.Role, parentResourceI
 	ret, err := resource.NewRoleResource(
 		displayName,
 		roleResourceType,
-		role.UID,
+		c.objectID(role.UID),
 		roleTraits,
-		resource.WithParentResourceID(parentResourceID),
+		resource.WithParentResourceID(c.parent()),
 	)
 	if err != nil {
 		return nil, err
@@ -78,8 +88,12 @@ func parseIntoRoleResource(_ context.Context, role *client.Role, parentResourceI
 
 func (o *roleBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var entitlements []*v2.Entitlement
-	role, _, err := o.client.GetRoleDetails(ctx, resource.Id.Resource)
 
+	c, roleID, err := o.clusters.resolve(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	role, err := o.getRole(ctx, c, roleID)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -98,80 +112,59 @@ func (o *roleBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _
 func (o *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var grants []*v2.Grant
 
-	// Note: Redis Enterprise Service API doesn't support pagination.
-	err := o.GetUsers(ctx)
+	c, roleID, err := o.clusters.resolve(resource.Id.Resource)
 	if err != nil {
 		return nil, "", nil, err
 	}
-	err = o.GetRoles(ctx)
+	roleUID, err := strconv.Atoi(roleID)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("baton-redis: invalid role id %s: %w", resource.Id.Resource, err)
+	}
+
+	// Note: Redis Enterprise Service API doesn't support pagination.
+	index, _, err := c.client.GetRoleIndex(ctx)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	for _, user := range o.users {
-		for _, roleUID := range user.RoleUIDs {
-			if strconv.Itoa(roleUID) == resource.Id.Resource {
-				userResource, _ := parseIntoUserResource(ctx, &user, nil)
-				role := o.roles[roleUID]
+	role := index.Roles[roleUID]
+	for _, user := range index.Users[roleUID] {
+		userID := &v2.ResourceId{ResourceType: userResourceType.Id, Resource: c.objectID(user.UID)}
 
-				userGrant := grant.NewGrant(resource, role.Management, userResource, grant.WithAnnotation(&v2.V1Identifier{
-					Id: fmt.Sprintf("role-grant:%s:%d:%s", resource.Id.Resource, user.UID, role.Management),
-				}))
-				grants = append(grants, userGrant)
-			}
-		}
+		userGrant := grant.NewGrant(resource, role.Management, userID, grant.WithAnnotation(&v2.V1Identifier{
+			Id: fmt.Sprintf("role-grant:%s:%s:%s", resource.Id.Resource, userID.Resource, role.Management),
+		}))
+		grants = append(grants, userGrant)
 	}
 
 	return grants, "", nil, nil
 }
 
-func newRoleBuilder(c *client.RedisClient) *roleBuilder {
-	return &roleBuilder{
-		resourceType: roleResourceType,
-		client:       c,
+// getRole looks the role up in the roles listed for this sync, only asking
+// the cluster for roles created since.
+func (o *roleBuilder) getRole(ctx context.Context, c *cluster, roleID string) (client.Role, error) {
+	index, _, err := c.client.GetRoleIndex(ctx)
+	if err != nil {
+		return client.Role{}, err
 	}
-}
-
-func (o *roleBuilder) GetUsers(ctx context.Context) error {
-	o.usersMutex.RLock()
-	defer o.usersMutex.RUnlock()
 
-	if o.users != nil || len(o.users) > 0 {
-		return nil
+	if roleUID, err := strconv.Atoi(roleID); err == nil {
+		if role, ok := index.Roles[roleUID]; ok {
+			return role, nil
+		}
 	}
 
-	users, _, err := o.client.ListUsers(ctx)
-
+	role, _, err := c.client.GetRoleDetails(ctx, roleID)
 	if err != nil {
-		return err
+		return client.Role{}, err
 	}
 
-	o.users = users
-
-	return nil
+	return role, nil
 }
 
-func (o *roleBuilder) GetRoles(ctx context.Context) error {
-	o.rolesMutex.RLock()
-	defer o.rolesMutex.RUnlock()
-
-	if o.roles == nil {
-		o.roles = make(map[int]client.Role)
-	}
-
-	if len(o.roles) > 0 {
-		return nil
-	}
-
-	roles, _, err := o.client.ListRoles(ctx)
-
-	if err != nil {
-		return err
-	}
-
-	for _, role := range roles {
-		o.roles[role.UID] = role
+func newRoleBuilder(clusters *clusterSet) *roleBuilder {
+	return &roleBuilder{
+		resourceType: roleResourceType,
+		clusters:     clusters,
 	}
-
-	return nil
 }
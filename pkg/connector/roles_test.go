@@ -3,15 +3,20 @@ package connector
 import (
 	"context"
 	encoding "encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/conductorone/baton-redis/pkg/client"
 	"github.com/conductorone/baton-redis/test"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 )
 
@@ -124,3 +129,184 @@ func TestRedisClient_GetRoles_RequestDetails(t *testing.T) {
 		}
 	}
 }
+
+// newCountingClient returns a client answering /v1/users and /v1/roles with
+// the given bodies, and the other collections as empty, and counting the
+// requests it sends.
+func newCountingClient(users, roles string, requests *int32) *client.RedisClient {
+	mockTransport := &test.MockRoundTripper{}
+	mockTransport.SetRoundTrip(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(requests, 1)
+
+		body := `{}`
+		switch req.URL.Path {
+		case "/v1/users":
+			body = users
+		case "/v1/roles":
+			body = roles
+		case "/v1/redis_acls", "/v1/bdbs", "/v1/ldap_mappings":
+			body = `[]`
+		}
+
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+		resp.Header.Set("Content-Type", "application/json")
+		return resp, nil
+	})
+
+	baseHttpClient := uhttp.NewBaseHttpClient(&http.Client{Transport: mockTransport})
+	return client.NewClient("username", "password", "http://localhost", "8080", baseHttpClient)
+}
+
+func TestRoleBuilder_EntitlementsAndGrants(t *testing.T) {
+	var requests int32
+	testClient := newCountingClient(test.ReadFile("usersMock.json"), test.ReadFile("rolesMock.json"), &requests)
+	ctx := context.Background()
+	testClient.InvalidateCache(ctx)
+
+	builder := newRoleBuilder(singleCluster(testClient))
+	roles, _, _, err := builder.List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var grantIDs []string
+	for _, role := range roles {
+		entitlements, _, _, err := builder.Entitlements(ctx, role, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(entitlements) != 1 {
+			t.Fatalf("Expected 1 entitlement for role %s, got %d", role.Id.Resource, len(entitlements))
+		}
+
+		grants, _, _, err := builder.Grants(ctx, role, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		for _, g := range grants {
+			var identifier v2.V1Identifier
+			grantAnnotations := annotations.Annotations(g.Annotations)
+			if ok, err := grantAnnotations.Pick(&identifier); !ok || err != nil {
+				t.Fatalf("Expected a V1Identifier on grant %s", g.Id)
+			}
+			grantIDs = append(grantIDs, identifier.Id)
+		}
+	}
+
+	expected := []string{
+		"role-grant:1:1:admin",
+		"role-grant:2:2:db_viewer",
+		"role-grant:3:2:cluster_viewer",
+		"role-grant:4:2:admin",
+	}
+	if !reflect.DeepEqual(grantIDs, expected) {
+		t.Errorf("Unexpected grants: got %v, want %v", grantIDs, expected)
+	}
+
+	// Roles and users, and the Redis ACLs, databases and LDAP mappings of the
+	// access report, are listed once for the whole sync.
+	if requests != 5 {
+		t.Errorf("Expected 5 requests, got %d", requests)
+	}
+}
+
+// generateUsersAndRoles returns the JSON listing of roleCount roles and
+// userCount users holding rolesPerUser roles each.
+func generateUsersAndRoles(userCount, roleCount, rolesPerUser int) (string, string) {
+	roles := make([]client.Role, roleCount)
+	for i := range roles {
+		roles[i] = client.Role{UID: i + 1, Name: fmt.Sprintf("role-%d", i+1), Management: test.ManagementRoles[i%len(test.ManagementRoles)]}
+	}
+
+	users := make([]client.User, userCount)
+	for i := range users {
+		users[i] = client.User{UID: i + 1, Name: fmt.Sprintf("user-%d", i+1), Status: "active"}
+		for j := 0; j < rolesPerUser; j++ {
+			users[i].RoleUIDs = append(users[i].RoleUIDs, (i+j)%roleCount+1)
+		}
+	}
+
+	usersJSON, _ := json.Marshal(users)
+	rolesJSON, _ := json.Marshal(roles)
+	return string(usersJSON), string(rolesJSON)
+}
+
+// grantsByScanning is how grants used to be computed: every user is scanned
+// for every role.
+func grantsByScanning(roles []client.Role, users []client.User) int {
+	count := 0
+	for _, role := range roles {
+		for _, user := range users {
+			for _, roleUID := range user.RoleUIDs {
+				if strconv.Itoa(roleUID) == strconv.Itoa(role.UID) {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// BenchmarkRoleBuilder_Grants compares computing the grants of every role by
+// scanning all users per role with looking them up in the role index.
+func BenchmarkRoleBuilder_Grants(b *testing.B) {
+	usersJSON, rolesJSON := generateUsersAndRoles(5000, 300, 3)
+
+	var users []client.User
+	var roles []client.Role
+	if err := json.Unmarshal([]byte(usersJSON), &users); err != nil {
+		b.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(rolesJSON), &roles); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if count := grantsByScanning(roles, users); count != 15000 {
+				b.Fatalf("Expected 15000 grants, got %d", count)
+			}
+		}
+	})
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			index := client.NewRoleIndex(roles, users)
+			count := 0
+			for _, role := range roles {
+				count += len(index.Users[role.UID])
+			}
+			if count != 15000 {
+				b.Fatalf("Expected 15000 grants, got %d", count)
+			}
+		}
+	})
+
+	b.Run("sync", func(b *testing.B) {
+		var requests int32
+		testClient := newCountingClient(usersJSON, rolesJSON, &requests)
+		builder := newRoleBuilder(singleCluster(testClient))
+		ctx := context.Background()
+
+		for i := 0; i < b.N; i++ {
+			testClient.InvalidateCache(ctx)
+			resources, _, _, err := builder.List(ctx, nil, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, resource := range resources {
+				if _, _, _, err := builder.Entitlements(ctx, resource, nil); err != nil {
+					b.Fatal(err)
+				}
+				if _, _, _, err := builder.Grants(ctx, resource, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.ReportMetric(float64(requests)/float64(b.N), "requests/sync")
+	})
+}
@@ -0,0 +1,197 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SecurityBaseline is the security posture clusters are expected to meet.
+// Settings left at their zero value aren't checked.
+type SecurityBaseline struct {
+	// PasswordMinLength is the shortest password length users may be allowed.
+	PasswordMinLength int `yaml:"password_min_length"`
+	// PasswordComplexity requires passwords to mix character classes.
+	PasswordComplexity bool `yaml:"password_complexity"`
+	// PasswordMaxAgeDays is the longest passwords may stay valid, in days.
+	PasswordMaxAgeDays int `yaml:"password_max_age_days"`
+	// LockoutThreshold is the most failed logins allowed before users are
+	// locked out.
+	LockoutThreshold int `yaml:"lockout_threshold"`
+	// LockoutDurationSeconds is the shortest time users may stay locked out.
+	LockoutDurationSeconds int `yaml:"lockout_duration_seconds"`
+	// MinControlTLSVersion and MinDataTLSVersion are the oldest TLS versions,
+	// such as "1.2", the API and the databases may accept.
+	MinControlTLSVersion string `yaml:"min_control_tls_version"`
+	MinDataTLSVersion    string `yaml:"min_data_tls_version"`
+	// RequireLDAP requires users to sign in through LDAP.
+	RequireLDAP bool `yaml:"require_ldap"`
+}
+
+// DefaultSecurityBaseline is the baseline clusters are checked against unless
+// the connector is configured with another one.
+var DefaultSecurityBaseline = SecurityBaseline{
+	PasswordMinLength:      8,
+	PasswordComplexity:     true,
+	PasswordMaxAgeDays:     90,
+	LockoutThreshold:       5,
+	LockoutDurationSeconds: 1800,
+	MinControlTLSVersion:   "1.2",
+	MinDataTLSVersion:      "1.2",
+}
+
+// defaultPasswordMinLength is the minimum password length of clusters that
+// don't report one.
+const defaultPasswordMinLength = 8
+
+// tlsVersions lists the TLS versions Redis Enterprise accepts as minimum
+// versions, oldest first.
+var tlsVersions = []string{"1", "1.1", "1.2", "1.3"}
+
+// Validate reports settings of the baseline the cluster couldn't be checked
+// against.
+func (b SecurityBaseline) Validate() error {
+	switch {
+	case b.PasswordMinLength < 0, b.PasswordMaxAgeDays < 0, b.LockoutThreshold < 0, b.LockoutDurationSeconds < 0:
+		return fmt.Errorf("baton-redis: security baseline settings must not be negative")
+	case b.MinControlTLSVersion != "" && tlsRank(b.MinControlTLSVersion) < 0:
+		return fmt.Errorf("baton-redis: unknown TLS version %q, expected one of %s", b.MinControlTLSVersion, strings.Join(tlsVersions, ", "))
+	case b.MinDataTLSVersion != "" && tlsRank(b.MinDataTLSVersion) < 0:
+		return fmt.Errorf("baton-redis: unknown TLS version %q, expected one of %s", b.MinDataTLSVersion, strings.Join(tlsVersions, ", "))
+	}
+	return nil
+}
+
+// tlsRank returns the position of a TLS version in tlsVersions, -1 when it is
+// unknown. "1.0" and "1" are the same version.
+func tlsRank(version string) int {
+	if version == "1.0" {
+		version = "1"
+	}
+	for rank, v := range tlsVersions {
+		if v == version {
+			return rank
+		}
+	}
+	return -1
+}
+
+// securityFinding is a cluster setting falling short of the baseline.
+type securityFinding struct {
+	setting  string
+	actual   interface{}
+	expected string
+}
+
+// securityPosture returns the security settings of the cluster, and the ones
+// falling short of the baseline. ldap is nil when the LDAP configuration
+// couldn't be read.
+func securityPosture(info *client.Cluster, ldap *client.LDAP, baseline SecurityBaseline) (map[string]interface{}, []securityFinding) {
+	minLength := info.PasswordMinLength
+	if minLength == 0 {
+		minLength = defaultPasswordMinLength
+	}
+
+	posture := map[string]interface{}{
+		"password_min_length":               minLength,
+		"password_complexity":               info.PasswordComplexity,
+		"password_expiration_days":          info.PasswordExpirationDuration,
+		"login_lockout_threshold":           info.LoginLockoutThreshold,
+		"login_lockout_duration":            info.LoginLockoutDuration,
+		"login_lockout_counter_reset_after": info.LoginLockoutCounterResetAfter,
+		"min_control_tls_version":           info.MinControlTLSVersion,
+		"min_data_tls_version":              info.MinDataTLSVersion,
+	}
+	if ldap != nil {
+		posture["ldap_enabled"] = ldap.Enabled()
+	}
+
+	var findings []securityFinding
+	add := func(setting string, actual interface{}, expected string) {
+		findings = append(findings, securityFinding{setting: setting, actual: actual, expected: expected})
+	}
+
+	if baseline.PasswordMinLength > 0 && minLength < baseline.PasswordMinLength {
+		add("password_min_length", minLength, fmt.Sprintf(">= %d", baseline.PasswordMinLength))
+	}
+	if baseline.PasswordComplexity && !info.PasswordComplexity {
+		add("password_complexity", false, "true")
+	}
+	// Passwords don't expire when the duration is 0.
+	if baseline.PasswordMaxAgeDays > 0 &&
+		(info.PasswordExpirationDuration == 0 || info.PasswordExpirationDuration > baseline.PasswordMaxAgeDays) {
+		add("password_expiration_days", info.PasswordExpirationDuration, fmt.Sprintf("1-%d", baseline.PasswordMaxAgeDays))
+	}
+	// Users are never locked out when the threshold is 0, and stay locked out
+	// until an admin unlocks them when the duration is.
+	if baseline.LockoutThreshold > 0 &&
+		(info.LoginLockoutThreshold == 0 || info.LoginLockoutThreshold > baseline.LockoutThreshold) {
+		add("login_lockout_threshold", info.LoginLockoutThreshold, fmt.Sprintf("1-%d", baseline.LockoutThreshold))
+	}
+	if baseline.LockoutDurationSeconds > 0 && info.LoginLockoutThreshold > 0 &&
+		info.LoginLockoutDuration > 0 && info.LoginLockoutDuration < baseline.LockoutDurationSeconds {
+		add("login_lockout_duration", info.LoginLockoutDuration, fmt.Sprintf("0 or >= %d", baseline.LockoutDurationSeconds))
+	}
+	if baseline.MinControlTLSVersion != "" && tlsRank(info.MinControlTLSVersion) < tlsRank(baseline.MinControlTLSVersion) {
+		add("min_control_tls_version", info.MinControlTLSVersion, ">= "+baseline.MinControlTLSVersion)
+	}
+	if baseline.MinDataTLSVersion != "" && tlsRank(info.MinDataTLSVersion) < tlsRank(baseline.MinDataTLSVersion) {
+		add("min_data_tls_version", info.MinDataTLSVersion, ">= "+baseline.MinDataTLSVersion)
+	}
+	if baseline.RequireLDAP && ldap != nil && !ldap.Enabled() {
+		add("ldap_enabled", false, "true")
+	}
+
+	return posture, findings
+}
+
+// securityDetails adds the security posture of the cluster, and its findings,
+// to the details of its resource.
+func securityDetails(details map[string]interface{}, posture map[string]interface{}, findings []securityFinding) {
+	details["security_posture"] = posture
+
+	list := []interface{}{}
+	for _, f := range findings {
+		list = append(list, map[string]interface{}{
+			"setting":  f.setting,
+			"actual":   f.actual,
+			"expected": f.expected,
+		})
+	}
+	details["security_findings"] = list
+	details["security_compliant"] = len(findings) == 0
+}
+
+// securityDescription summarizes the findings for the description of the
+// cluster resource.
+func securityDescription(findings []securityFinding) string {
+	if len(findings) == 0 {
+		return "Meets the security baseline"
+	}
+
+	settings := make([]string, 0, len(findings))
+	for _, f := range findings {
+		settings = append(settings, f.setting)
+	}
+	return fmt.Sprintf("Below the security baseline: %s", strings.Join(settings, ", "))
+}
+
+// getLDAP returns the LDAP configuration of cluster c, nil when the connector
+// isn't allowed to read it.
+func getLDAP(ctx context.Context, c *cluster) (*client.LDAP, error) {
+	ldap, _, err := c.client.GetLDAP(ctx)
+	if status.Code(err) == codes.PermissionDenied {
+		ctxzap.Extract(ctx).Warn("baton-redis: unable to read the LDAP configuration, skipping LDAP posture", zap.String("cluster", c.id), zap.Error(err))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ldap, nil
+}
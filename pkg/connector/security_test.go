@@ -0,0 +1,132 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+func TestSecurityPosture(t *testing.T) {
+	compliant := client.Cluster{
+		PasswordComplexity:         true,
+		PasswordMinLength:          12,
+		PasswordExpirationDuration: 60,
+		LoginLockoutThreshold:      5,
+		LoginLockoutDuration:       0,
+		MinControlTLSVersion:       "1.2",
+		MinDataTLSVersion:          "1.3",
+	}
+
+	testCases := []struct {
+		name     string
+		update   func(*client.Cluster)
+		ldap     *client.LDAP
+		baseline SecurityBaseline
+		expected []string
+	}{
+		{
+			name:     "compliant",
+			update:   func(*client.Cluster) {},
+			baseline: DefaultSecurityBaseline,
+		},
+		{
+			name: "defaults of a new cluster",
+			update: func(c *client.Cluster) {
+				*c = client.Cluster{}
+			},
+			baseline: DefaultSecurityBaseline,
+			expected: []string{"password_complexity", "password_expiration_days", "login_lockout_threshold", "min_control_tls_version", "min_data_tls_version"},
+		},
+		{
+			name: "weak settings",
+			update: func(c *client.Cluster) {
+				c.PasswordMinLength = 6
+				c.PasswordExpirationDuration = 365
+				c.LoginLockoutThreshold = 10
+				c.LoginLockoutDuration = 60
+				c.MinDataTLSVersion = "1"
+			},
+			baseline: DefaultSecurityBaseline,
+			expected: []string{"password_min_length", "password_expiration_days", "login_lockout_threshold", "login_lockout_duration", "min_data_tls_version"},
+		},
+		{
+			name:     "LDAP required",
+			update:   func(*client.Cluster) {},
+			ldap:     &client.LDAP{URIs: []string{"ldaps://ldap.example.com"}},
+			baseline: SecurityBaseline{RequireLDAP: true},
+			expected: []string{"ldap_enabled"},
+		},
+		{
+			name: "checks disabled",
+			update: func(c *client.Cluster) {
+				*c = client.Cluster{}
+			},
+			baseline: SecurityBaseline{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := compliant
+			tc.update(&info)
+
+			_, findings := securityPosture(&info, tc.ldap, tc.baseline)
+			var settings []string
+			for _, f := range findings {
+				settings = append(settings, f.setting)
+			}
+			if !slices.Equal(settings, tc.expected) {
+				t.Errorf("Expected findings %v, got %v", tc.expected, settings)
+			}
+		})
+	}
+}
+
+func TestClusterBuilder_SecurityPosture(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.SetCluster(fakeserver.Object{
+		"password_complexity":          true,
+		"password_expiration_duration": 30,
+		"login_lockout_threshold":      5,
+		"login_lockout_duration":       3600,
+		"min_control_TLS_version":      "1.2",
+		"min_data_TLS_version":         "1.1",
+	})
+	fake.SetLDAP(fakeserver.Object{"uris": []any{"ldaps://ldap.example.com"}, "control_plane": true})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	clusters := singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{})))
+	resources, _, _, err := newClusterBuilder(clusters, DefaultSecurityBaseline).List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(resources) != 1 {
+		t.Fatalf("Expected 1 cluster, got %d", len(resources))
+	}
+
+	details := resourceDetails(t, resources[0])
+	posture, _ := details["security_posture"].(map[string]interface{})
+	if posture["ldap_enabled"] != true || posture["password_min_length"] != float64(8) || posture["login_lockout_duration"] != float64(3600) {
+		t.Errorf("Expected the cluster security posture, got %v", posture)
+	}
+
+	findings, _ := details["security_findings"].([]any)
+	if len(findings) != 1 || details["security_compliant"] != false {
+		t.Fatalf("Expected a single finding, got %v", findings)
+	}
+	finding, _ := findings[0].(map[string]any)
+	if finding["setting"] != "min_data_tls_version" || finding["actual"] != "1.1" || finding["expected"] != ">= 1.2" {
+		t.Errorf("Expected the data plane TLS version finding, got %v", finding)
+	}
+	if resources[0].Description != "Below the security baseline: min_data_tls_version" {
+		t.Errorf("Expected the findings in the description, got %q", resources[0].Description)
+	}
+}
@@ -0,0 +1,144 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-redis/test"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+func TestConnector_Sync(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.AddCRDB(fakeserver.Object{
+		"guid": "crdb-1",
+		"name": "sessions",
+		"instances": []any{
+			fakeserver.Object{"id": 1, "cluster": fakeserver.Object{"name": "cluster.local", "url": "https://cluster.local:9443"}, "db_uid": "1"},
+			fakeserver.Object{"id": 2, "cluster": fakeserver.Object{"name": "cluster2.local", "url": "https://cluster2.local:9443"}, "db_uid": "4"},
+		},
+		"local_databases": []any{fakeserver.Object{"id": 1, "bdb_uid": "1"}},
+	})
+	if err := fake.Update(fakeserver.Users, 2, fakeserver.Object{
+		"email_alerts":         true,
+		"cluster_email_alerts": true,
+		"bdbs_email_alerts":    []any{"1"},
+	}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	redisConnector, err := New(ctx, client.NewClient("admin@example.com", "password", server.URL, ""), false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	connectorServer, err := connectorbuilder.NewConnector(ctx, redisConnector)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	contents := test.ReadC1Z(ctx, t, test.Sync(ctx, t, connectorServer))
+
+	var resources []string
+	byKey := map[string]*v2.Resource{}
+	for _, resource := range contents.Resources {
+		resources = append(resources, test.ResourceKey(resource.Id)+" "+resource.DisplayName)
+		byKey[test.ResourceKey(resource.Id)] = resource
+	}
+	expectedResources := []string{
+		"cluster:cluster.local cluster.local",
+		"crdb:crdb-1 sessions",
+		"crdb_instance:crdb-1:1 sessions on cluster.local",
+		"crdb_instance:crdb-1:2 sessions on cluster2.local",
+		"database:1 db1",
+		"redis_acl:1 Full Access",
+		"redis_acl:2 Read Only",
+		"role:1 Admin",
+		"role:2 Viewer",
+		"role:3 DB Member",
+		"user:1 Admin",
+		"user:2 Viewer",
+	}
+	if !slices.Equal(resources, expectedResources) {
+		t.Errorf("Expected resources %v, got %v", expectedResources, resources)
+	}
+
+	userTrait, err := resource.GetUserTrait(byKey["user:1"])
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for key, expected := range map[string]string{"email": "admin@example.com", "auth_method": "regular", "status": "active"} {
+		if value, _ := resource.GetProfileStringValue(userTrait.Profile, key); value != expected {
+			t.Errorf("Expected user profile %s to be %s, got %s", key, expected, value)
+		}
+	}
+
+	var entitlements []string
+	for _, entitlement := range contents.Entitlements {
+		entitlements = append(entitlements, entitlement.Id)
+	}
+	expectedEntitlements := []string{
+		"cluster:cluster.local:email_alerts",
+		"database:1:email_alerts",
+		"role:1:admin",
+		"role:2:cluster_viewer",
+		"role:3:db_member",
+		"user:1:locked",
+		"user:2:locked",
+	}
+	if !slices.Equal(entitlements, expectedEntitlements) {
+		t.Errorf("Expected entitlements %v, got %v", expectedEntitlements, entitlements)
+	}
+
+	var grants []string
+	for _, grant := range contents.Grants {
+		grants = append(grants, grant.Id+" "+test.V1Identifier(grant))
+	}
+	expectedGrants := []string{
+		"cluster:cluster.local:email_alerts:user:2 ",
+		"database:1:email_alerts:user:2 ",
+		"role:1:admin:user:1 role-grant:1:1:admin",
+		"role:2:cluster_viewer:user:2 role-grant:2:2:cluster_viewer",
+		"role:3:db_member:user:2 role-grant:3:2:db_member",
+	}
+	if !slices.Equal(grants, expectedGrants) {
+		t.Errorf("Expected grants %v, got %v", expectedGrants, grants)
+	}
+}
+
+// TestConnector_SyncFixtures syncs against the responses recorded from every
+// cluster version in test/fixtures.
+func TestConnector_SyncFixtures(t *testing.T) {
+	versions, err := test.FixtureVersions()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, version := range versions {
+		t.Run(version, func(t *testing.T) {
+			ctx := context.Background()
+
+			redisClient, err := test.NewReplayClient(version)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			connectorServer, err := connectorbuilder.NewConnector(ctx, &Connector{clusters: singleCluster(redisClient)})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			contents := test.ReadC1Z(ctx, t, test.Sync(ctx, t, connectorServer))
+			if len(contents.Resources) == 0 || len(contents.Grants) == 0 {
+				t.Errorf("Expected resources and grants, got %d resources and %d grants", len(contents.Resources), len(contents.Grants))
+			}
+		})
+	}
+}
@@ -2,19 +2,35 @@ package connector
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/conductorone/baton-redis/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	lockedEntitlement = "locked"
+	userStatusActive  = "active"
+	userStatusLocked  = "locked"
 )
 
 type userBuilder struct {
 	resourceType *v2.ResourceType
-	client       *client.RedisClient
+	clusters     *clusterSet
+	thresholds   AccountThresholds
+	now          func() time.Time
 }
 
 func (o *userBuilder) ResourceType(_ context.Context) *v2.ResourceType {
@@ -23,18 +39,50 @@ func (o *userBuilder) ResourceType(_ context.Context) *v2.ResourceType {
 
 // List returns all the users from the database as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
-func (o *userBuilder) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+// Their last login is read from the event log, from the dormancy threshold on,
+// unless dormancy isn't checked.
+func (o *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
 	var resources []*v2.Resource
 
+	c := o.clusters.forParent(parentResourceID)
+	if c == nil {
+		return nil, "", nil, nil
+	}
+
 	// Note: Redis Enterprise Service API doesn't support pagination.
-	users, annotation, err := o.client.ListUsers(ctx)
+	users, annotation, err := c.client.ListUsers(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	cluster, _, err := c.client.GetCluster(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	report, err := getAccessReport(ctx, c)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	now := o.now()
+	var activity *loginActivity
+	if o.thresholds.DormantDays > 0 {
+		activity = readLoginActivity(ctx, c, users, now.AddDate(0, 0, -o.thresholds.DormantDays), now)
+	}
+
 	for _, user := range users {
 		userCopy := user
-		userResource, err := parseIntoUserResource(ctx, &userCopy, nil)
+		userResource, err := parseIntoUserResource(
+			ctx,
+			c,
+			&userCopy,
+			cluster.PasswordExpirationDuration,
+			report,
+			activity,
+			o.thresholds,
+			now,
+		)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -44,31 +92,72 @@ func (o *userBuilder) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.
 	return resources, "", annotation, nil
 }
 
-func parseIntoUserResource(_ context.Context, user *client.User, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
-	var userStatus = v2.UserTrait_Status_STATUS_ENABLED
+// parseIntoUserResource builds the resource of a user of cluster c.
+// clusterPasswordDays is the cluster password expiration policy, used for
+// users without their own. The findings of report about the user, such as
+// deleted roles it still references, are added to its profile, along with its
+// last login from activity and whether it crossed the thresholds.
+func parseIntoUserResource(
+	ctx context.Context,
+	c *cluster,
+	user *client.User,
+	clusterPasswordDays int,
+	report *accessReport,
+	activity *loginActivity,
+	thresholds AccountThresholds,
+	now time.Time,
+) (*v2.Resource, error) {
+	passwordExpiresAt := passwordExpiry(user, clusterPasswordDays)
+	userStatus, statusDetails := userStatus(ctx, user, passwordExpiresAt, now)
 
 	profile := map[string]interface{}{
-		"user_id":         user.UID,
-		"username":        user.Name,
-		"email":           user.Email,
-		"management_role": user.Role,
-		"role_uids":       parseRoleUIDs(user.RoleUIDs),
+		"user_id":              user.UID,
+		"username":             user.Name,
+		"email":                user.Email,
+		"management_role":      user.Role,
+		"role_uids":            parseRoleUIDs(user.RoleUIDs),
+		"auth_method":          user.AuthMethod,
+		"status":               user.Status,
+		"email_alerts":         user.EmailAlerts,
+		"cluster_email_alerts": user.ClusterEmailAlerts,
+		"bdbs_email_alerts":    strings.Join(user.BdbsEmailAlerts, ","),
+	}
+	if user.AccountID != 0 {
+		profile["account_id"] = user.AccountID
+	}
+	if user.CertificateSubjectLine != "" {
+		profile["certificate_subject_line"] = user.CertificateSubjectLine
+	}
+	if !user.PasswordIssueDate.IsZero() {
+		profile["password_issue_date"] = user.PasswordIssueDate.Format(time.RFC3339)
+	}
+	if user.PasswordExpirationDuration != 0 {
+		profile["password_expiration_duration"] = user.PasswordExpirationDuration
 	}
+	if !passwordExpiresAt.IsZero() {
+		profile["password_expires_at"] = passwordExpiresAt.Format(time.RFC3339)
+		profile["password_expired"] = !now.Before(passwordExpiresAt)
+	}
+	report.annotate(profile, userResourceType.Id, user.UID)
+	lastLogin := accountActivity(profile, user, activity, thresholds, now)
 
 	userTraits := []resource.UserTraitOption{
 		resource.WithUserProfile(profile),
-		resource.WithStatus(userStatus),
+		resource.WithDetailedStatus(userStatus, statusDetails),
 		resource.WithUserLogin(user.Name),
 	}
+	if !lastLogin.IsZero() {
+		userTraits = append(userTraits, resource.WithLastLogin(lastLogin))
+	}
 
 	displayName := user.Name
 
 	ret, err := resource.NewUserResource(
 		displayName,
 		userResourceType,
-		user.UID,
+		c.objectID(user.UID),
 		userTraits,
-		resource.WithParentResourceID(parentResourceID),
+		resource.WithParentResourceID(c.parent()),
 	)
 	if err != nil {
 		return nil, err
@@ -77,20 +166,101 @@ func parseIntoUserResource(_ context.Context, user *client.User, parentResourceI
 	return ret, nil
 }
 
-// Entitlements always returns an empty slice for users.
-func (o *userBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+// Entitlements returns the locked entitlement of the user. Granting it locks
+// the account and revoking it unlocks the account, which is how suspending a
+// user without deleting it is exposed to provisioning.
+func (o *userBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	lockedOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(userResourceType),
+		entitlement.WithDescription(fmt.Sprintf("User %s is locked out of the Redis cluster", resource.DisplayName)),
+		entitlement.WithDisplayName(fmt.Sprintf("%s Locked", resource.DisplayName)),
+	}
+
+	return []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(resource, lockedEntitlement, lockedOptions...),
+	}, "", nil, nil
 }
 
-// Grants always returns an empty slice for users since they don't have any entitlements.
-func (o *userBuilder) Grants(ctx context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+// Grants returns the locked entitlement granted to the user itself when the
+// account is locked.
+func (o *userBuilder) Grants(_ context.Context, userResource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	userTrait, err := resource.GetUserTrait(userResource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if userTrait.GetStatus().GetDetails() != lockedEntitlement {
+		return nil, "", nil, nil
+	}
+
+	return []*v2.Grant{grant.NewGrant(userResource, lockedEntitlement, userResource.Id)}, "", nil, nil
+}
+
+// Grant locks the user. Only the user itself can be granted its locked
+// entitlement.
+func (o *userBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	return o.setLocked(ctx, principal.Id, entitlement, true)
+}
+
+// Revoke unlocks the user.
+func (o *userBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	return o.setLocked(ctx, grant.Principal.Id, grant.Entitlement, false)
 }
 
-func newUserBuilder(c *client.RedisClient) *userBuilder {
+// setLocked locks or unlocks a user, doing nothing when the account is already
+// in that state.
+func (o *userBuilder) setLocked(ctx context.Context, principal *v2.ResourceId, entitlement *v2.Entitlement, locked bool) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if principal.ResourceType != userResourceType.Id || principal.Resource != entitlement.Resource.Id.Resource {
+		return nil, status.Errorf(codes.InvalidArgument, "baton-redis: the locked entitlement of user %s can only be granted to the user itself", entitlement.Resource.Id.Resource)
+	}
+
+	c, uid, err := o.clusters.resolve(principal.Resource)
+	if err != nil {
+		return nil, err
+	}
+	userUID, err := strconv.Atoi(uid)
+	if err != nil {
+		return nil, fmt.Errorf("baton-redis: invalid user id %s: %w", principal.Resource, err)
+	}
+
+	user, _, err := c.client.GetUser(ctx, userUID)
+	if err != nil {
+		return nil, err
+	}
+
+	annos := annotations.Annotations{}
+	isLocked := isLockedStatus(user.Status)
+	switch {
+	case locked && isLocked:
+		annos.Update(&v2.GrantAlreadyExists{})
+		return annos, nil
+	case !locked && !isLocked:
+		annos.Update(&v2.GrantAlreadyRevoked{})
+		return annos, nil
+	}
+
+	newStatus := userStatusActive
+	if locked {
+		newStatus = userStatusLocked
+	}
+
+	if _, _, err := c.client.UpdateUser(ctx, userUID, map[string]any{"status": newStatus}); err != nil {
+		return nil, err
+	}
+
+	l.Info("baton-redis: changed user status", zap.String("user", principal.Resource), zap.String("status", newStatus))
+
+	return annos, nil
+}
+
+func newUserBuilder(clusters *clusterSet, thresholds AccountThresholds) *userBuilder {
 	return &userBuilder{
 		resourceType: userResourceType,
-		client:       c,
+		clusters:     clusters,
+		thresholds:   thresholds,
+		now:          time.Now,
 	}
 }
 
@@ -101,3 +271,61 @@ func parseRoleUIDs(roles []int) string {
 	}
 	return strings.Join(rolesStr, ",")
 }
+
+// passwordExpiry returns when the password of a user expires, or the zero time
+// when it doesn't. A password expiration set on the user overrides the cluster
+// policy.
+func passwordExpiry(user *client.User, clusterPasswordDays int) time.Time {
+	days := user.PasswordExpirationDuration
+	if days == 0 {
+		days = clusterPasswordDays
+	}
+	if days <= 0 || user.PasswordIssueDate.IsZero() {
+		return time.Time{}
+	}
+
+	return user.PasswordIssueDate.AddDate(0, 0, days)
+}
+
+// userStatus maps the status the cluster reports for a user to a trait status,
+// with the reason the user can't sign in as details. Users without a status
+// are active, as the cluster leaves it out for them. Active users whose
+// password has expired are disabled, since they can't sign in until it is
+// reset.
+func userStatus(ctx context.Context, user *client.User, passwordExpiresAt time.Time, now time.Time) (v2.UserTrait_Status_Status, string) {
+	switch normalizeStatus(user.Status) {
+	case userStatusActive, "":
+		if !passwordExpiresAt.IsZero() && !now.Before(passwordExpiresAt) {
+			return v2.UserTrait_Status_STATUS_DISABLED, "password_expired"
+		}
+		return v2.UserTrait_Status_STATUS_ENABLED, ""
+	case "locked", "lockout", "locked_out":
+		return v2.UserTrait_Status_STATUS_DISABLED, lockedEntitlement
+	case "pending":
+		return v2.UserTrait_Status_STATUS_DISABLED, "pending"
+	case "password_expired", "expired":
+		return v2.UserTrait_Status_STATUS_DISABLED, "password_expired"
+	case "deleted":
+		return v2.UserTrait_Status_STATUS_DELETED, ""
+	default:
+		ctxzap.Extract(ctx).Warn(
+			"baton-redis: unknown user status",
+			zap.Int("user_id", user.UID),
+			zap.String("status", user.Status),
+		)
+		return v2.UserTrait_Status_STATUS_UNSPECIFIED, user.Status
+	}
+}
+
+func normalizeStatus(status string) string {
+	return strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(status)))
+}
+
+func isLockedStatus(status string) bool {
+	switch normalizeStatus(status) {
+	case "locked", "lockout", "locked_out":
+		return true
+	default:
+		return false
+	}
+}
@@ -7,11 +7,16 @@ import (
 	"io"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/conductorone/baton-redis/pkg/client"
 	"github.com/conductorone/baton-redis/test"
+	"github.com/conductorone/baton-redis/test/fakeserver"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 )
 
@@ -130,3 +135,283 @@ func TestRedisClient_GetUsers_RequestDetails(t *testing.T) {
 		}
 	}
 }
+
+func TestUserStatus(t *testing.T) {
+	now := test.GetUniqueTime()
+	expired := now.Add(-time.Hour)
+	valid := now.Add(time.Hour)
+
+	testCases := []struct {
+		status          string
+		expiresAt       time.Time
+		expectedStatus  v2.UserTrait_Status_Status
+		expectedDetails string
+	}{
+		{status: "active", expectedStatus: v2.UserTrait_Status_STATUS_ENABLED},
+		{status: "", expectedStatus: v2.UserTrait_Status_STATUS_ENABLED},
+		{status: "", expiresAt: expired, expectedStatus: v2.UserTrait_Status_STATUS_DISABLED, expectedDetails: "password_expired"},
+		{status: "active", expiresAt: valid, expectedStatus: v2.UserTrait_Status_STATUS_ENABLED},
+		{status: "active", expiresAt: expired, expectedStatus: v2.UserTrait_Status_STATUS_DISABLED, expectedDetails: "password_expired"},
+		{status: "locked", expectedStatus: v2.UserTrait_Status_STATUS_DISABLED, expectedDetails: "locked"},
+		{status: "lockout", expectedStatus: v2.UserTrait_Status_STATUS_DISABLED, expectedDetails: "locked"},
+		{status: "pending", expectedStatus: v2.UserTrait_Status_STATUS_DISABLED, expectedDetails: "pending"},
+		{status: "password-expired", expectedStatus: v2.UserTrait_Status_STATUS_DISABLED, expectedDetails: "password_expired"},
+		{status: "deleted", expectedStatus: v2.UserTrait_Status_STATUS_DELETED},
+		{status: "suspended", expectedStatus: v2.UserTrait_Status_STATUS_UNSPECIFIED, expectedDetails: "suspended"},
+	}
+
+	for _, tc := range testCases {
+		status, details := userStatus(context.Background(), &client.User{Status: tc.status}, tc.expiresAt, now)
+		if status != tc.expectedStatus || details != tc.expectedDetails {
+			t.Errorf("%s (expires %v): expected %v %q, got %v %q", tc.status, tc.expiresAt, tc.expectedStatus, tc.expectedDetails, status, details)
+		}
+	}
+}
+
+func TestPasswordExpiry(t *testing.T) {
+	issued := test.GetUniqueTime()
+
+	testCases := []struct {
+		name        string
+		user        client.User
+		clusterDays int
+		expected    time.Time
+	}{
+		{name: "no policy", user: client.User{PasswordIssueDate: issued}},
+		{name: "cluster policy", user: client.User{PasswordIssueDate: issued}, clusterDays: 90, expected: issued.AddDate(0, 0, 90)},
+		{
+			name:        "user policy wins",
+			user:        client.User{PasswordIssueDate: issued, PasswordExpirationDuration: 30},
+			clusterDays: 90,
+			expected:    issued.AddDate(0, 0, 30),
+		},
+		{name: "never issued", user: client.User{}, clusterDays: 90},
+	}
+
+	for _, tc := range testCases {
+		if got := passwordExpiry(&tc.user, tc.clusterDays); !got.Equal(tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestUserBuilder_ListStatus(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	fake.SetCluster(fakeserver.Object{"password_expiration_duration": 90})
+	if err := fake.Update(fakeserver.Users, 2, fakeserver.Object{"status": "locked"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	builder := newUserBuilder(singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))), DefaultAccountThresholds)
+	builder.now = func() time.Time { return time.Now().AddDate(0, 0, 91) }
+
+	resources, _, _, err := builder.List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := map[string]string{"1": "password_expired", "2": "locked"}
+	for _, r := range resources {
+		userTrait, err := resource.GetUserTrait(r)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if userTrait.Status.Status != v2.UserTrait_Status_STATUS_DISABLED || userTrait.Status.Details != expected[r.Id.Resource] {
+			t.Errorf("User %s: expected disabled %q, got %v", r.Id.Resource, expected[r.Id.Resource], userTrait.Status)
+		}
+		if !userTrait.Profile.GetFields()["password_expired"].GetBoolValue() {
+			t.Errorf("User %s: expected the password to be reported expired", r.Id.Resource)
+		}
+	}
+}
+
+func TestUserBuilder_LockAndUnlock(t *testing.T) {
+	ctx := context.Background()
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	builder := newUserBuilder(singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))), DefaultAccountThresholds)
+
+	resources, _, _, err := builder.List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	viewer := resources[1]
+	entitlements, _, _, err := builder.Entitlements(ctx, viewer, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	locked := entitlements[0]
+
+	status := func() string {
+		userStatus, _ := fake.Get(fakeserver.Users, 2)["status"].(string)
+		return userStatus
+	}
+
+	annos, err := builder.Grant(ctx, viewer, locked)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status() != "locked" || annos.Contains(&v2.GrantAlreadyExists{}) {
+		t.Fatalf("Expected the user to be locked, got %s", status())
+	}
+
+	annos, err = builder.Grant(ctx, viewer, locked)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !annos.Contains(&v2.GrantAlreadyExists{}) {
+		t.Errorf("Expected locking a locked user to report GrantAlreadyExists")
+	}
+
+	// The next sync reports the user locked and the grant.
+	resources, _, _, err = builder.List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	grants, _, _, err := builder.Grants(ctx, resources[1], nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(grants) != 1 || grants[0].Id != "user:2:locked:user:2" {
+		t.Fatalf("Expected the locked grant, got %v", grants)
+	}
+
+	if _, err := builder.Revoke(ctx, grants[0]); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status() != "active" {
+		t.Errorf("Expected the user to be unlocked, got %s", status())
+	}
+
+	annos, err = builder.Revoke(ctx, grants[0])
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !annos.Contains(&v2.GrantAlreadyRevoked{}) {
+		t.Errorf("Expected unlocking an active user to report GrantAlreadyRevoked")
+	}
+
+	if _, err := builder.Grant(ctx, resources[0], locked); err == nil {
+		t.Errorf("Expected locking another user through this entitlement to fail")
+	}
+}
+
+func TestUserBuilder_ListAccountActivity(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	if err := fake.Update(fakeserver.Users, 2, fakeserver.Object{"password_issue_date": now.AddDate(0, 0, -100).Format(time.RFC3339)}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	newcomer := fake.MustAdd(fakeserver.Users, fakeserver.Object{"name": "Newcomer", "email": "newcomer@example.com"})
+	fake.AddLog(fakeserver.Object{"type": "user_login", "user_uid": "1", "time": now.AddDate(0, 0, -200).Format(time.RFC3339)})
+	fake.AddLog(fakeserver.Object{"type": "user_login", "user_uid": "1", "time": now.AddDate(0, 0, -10).Format(time.RFC3339)})
+	fake.AddLog(fakeserver.Object{"type": "login_failed", "user_name": "viewer@example.com", "time": now.AddDate(0, 0, -5).Format(time.RFC3339)})
+	fake.AddLog(fakeserver.Object{"type": "user_created", "user_uid": strconv.Itoa(newcomer), "time": now.AddDate(0, 0, -3).Format(time.RFC3339)})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	builder := newUserBuilder(singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{}))), DefaultAccountThresholds)
+	builder.now = func() time.Time { return now }
+
+	resources, _, _, err := builder.List(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	traits := map[string]*v2.UserTrait{}
+	for _, r := range resources {
+		userTrait, err := resource.GetUserTrait(r)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		traits[r.Id.Resource] = userTrait
+	}
+
+	admin := traits["1"]
+	if !admin.LastLogin.AsTime().Equal(now.AddDate(0, 0, -10)) {
+		t.Errorf("Expected the last login of the admin, got %v", admin.LastLogin)
+	}
+	if profile := admin.Profile.AsMap(); profile["dormant"] != false || profile["stale_password"] != false || profile["password_age_days"] != float64(0) {
+		t.Errorf("Expected the admin to be active, got %v", profile)
+	}
+
+	viewer := traits["2"]
+	if viewer.LastLogin != nil {
+		t.Errorf("Expected a failed login not to count, got %v", viewer.LastLogin)
+	}
+	if profile := viewer.Profile.AsMap(); profile["dormant"] != true || profile["stale_password"] != true || profile["password_age_days"] != float64(100) {
+		t.Errorf("Expected the viewer to be dormant with a stale password, got %v", profile)
+	}
+
+	if profile := traits[strconv.Itoa(newcomer)].Profile.AsMap(); profile["dormant"] != false {
+		t.Errorf("Expected a user created recently not to be dormant, got %v", profile)
+	}
+}
+
+func TestUserBuilder_ListAccountActivityWithShortLog(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	fake := fakeserver.NewWithDefaults("admin@example.com", "password")
+	// The log only goes back 10 days, less than the dormancy threshold.
+	fake.AddLog(fakeserver.Object{"type": "user_login", "user_uid": "1", "time": now.AddDate(0, 0, -10).Format(time.RFC3339)})
+	server := fake.Start()
+	t.Cleanup(server.Close)
+
+	clusters := singleCluster(client.NewClient("admin@example.com", "password", server.URL, "", uhttp.NewBaseHttpClient(&http.Client{})))
+
+	testCases := []struct {
+		name          string
+		thresholds    AccountThresholds
+		expectedLogin bool
+		logRequests   int
+	}{
+		{name: "dormancy checked", thresholds: DefaultAccountThresholds, expectedLogin: true, logRequests: 2},
+		{name: "dormancy not checked", thresholds: AccountThresholds{StalePasswordDays: 90}, logRequests: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			before := len(fake.Requests())
+
+			builder := newUserBuilder(clusters, tc.thresholds)
+			builder.now = func() time.Time { return now }
+			resources, _, _, err := builder.List(ctx, nil, nil)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			logRequests := 0
+			for _, request := range fake.Requests()[before:] {
+				if request == "GET /v1/logs" {
+					logRequests++
+				}
+			}
+			if logRequests != tc.logRequests {
+				t.Errorf("Expected %d event log requests, got %d", tc.logRequests, logRequests)
+			}
+
+			for _, r := range resources {
+				userTrait, err := resource.GetUserTrait(r)
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if dormant, ok := userTrait.Profile.AsMap()["dormant"]; ok {
+					t.Errorf("Expected %s not to be flagged, got dormant %v", r.DisplayName, dormant)
+				}
+				if r.Id.Resource == "1" && (userTrait.LastLogin != nil) != tc.expectedLogin {
+					t.Errorf("Expected the last login of the admin to be reported %t, got %v", tc.expectedLogin, userTrait.LastLogin)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-redis/pkg/audit"
+)
+
+// AuditFramesFile holds database connection audit records as a cluster sends
+// them, for replaying into an audit.Receiver.
+const AuditFramesFile = "../../test/audit/db_conns.jsonl"
+
+// ReplayAudit sends the audit records in file to receiver over a single
+// connection, as a cluster would, and waits until receiver has them all.
+func ReplayAudit(t *testing.T, receiver *audit.Receiver, file string) {
+	t.Helper()
+
+	frames, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := receiver.Last()
+	for _, b := range frames {
+		if b == '\n' {
+			want++
+		}
+	}
+
+	addr := receiver.Addr()
+	conn, err := net.Dial(addr.Network(), addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write(frames); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for receiver.Last() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d audit records, expected %d", receiver.Last(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,71 @@
+// Command redis-fakeserver serves the fake Redis Enterprise REST API for demos
+// and local development:
+//
+//	go run ./test/fakeserver/cmd/redis-fakeserver -addr 127.0.0.1:9443
+//	baton-redis --cluster-host https://127.0.0.1 --api-port 9443 \
+//	    --username admin@example.com --password password
+//
+// The connector has to trust the self-signed certificate, or run the server
+// with -tls=false and point the connector to http://127.0.0.1.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/conductorone/baton-redis/test/fakeserver"
+)
+
+func main() {
+	var (
+		addr     = flag.String("addr", "127.0.0.1:9443", "address to listen on")
+		username = flag.String("username", "admin@example.com", "email of the seeded admin user")
+		password = flag.String("password", "password", "password of the seeded admin user")
+		seed     = flag.String("seed", "", "JSON file with the cluster content, replaces the default content")
+		useTLS   = flag.Bool("tls", true, "serve https with a self-signed certificate")
+		latency  = flag.Duration("latency", 0, "delay added to every response")
+	)
+	flag.Parse()
+
+	server := fakeserver.NewWithDefaults(*username, *password)
+	if *seed != "" {
+		server = fakeserver.New()
+		f, err := os.Open(*seed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = server.Load(f)
+		_ = f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	server.SetLatency(*latency)
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	httpServer := httptest.NewUnstartedServer(server.Handler())
+	httpServer.Listener = listener
+	httpServer.Config.ReadHeaderTimeout = 10 * time.Second
+	if *useTLS {
+		httpServer.StartTLS()
+	} else {
+		httpServer.Start()
+	}
+	defer httpServer.Close()
+
+	fmt.Printf("fake Redis Enterprise cluster listening on %s\n", httpServer.URL)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	<-stop
+}
@@ -0,0 +1,507 @@
+package fakeserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errNotFound = errors.New("not found")
+
+// writeManagementRoles are the management roles allowed to change objects.
+var writeManagementRoles = map[string]bool{
+	"admin":        true,
+	"user_manager": true,
+}
+
+// Handler returns the http.Handler serving the fake cluster API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /v1/users/authorize", s.handleAuthorize)
+	mux.HandleFunc("GET /v1/cluster", s.authenticated(s.handleCluster))
+	mux.HandleFunc("GET /v1/cluster/ldap", s.authenticated(s.handleLDAP))
+	mux.HandleFunc("GET /v1/cluster/certificates", s.authenticated(s.handleCertificates))
+	mux.HandleFunc("GET /v1/logs", s.authenticated(s.handleLogs))
+	mux.HandleFunc("GET /v1/crdbs", s.authenticated(s.handleCRDBs))
+	mux.HandleFunc("GET /v1/crdbs/{guid}", s.authenticated(s.handleCRDB))
+
+	for _, collection := range collections {
+		prefix := "/v1/" + collection
+		mux.HandleFunc("GET "+prefix, s.authenticated(s.handleList(collection)))
+		mux.HandleFunc("GET "+prefix+"/{uid}", s.authenticated(s.handleGet(collection)))
+		if collection == Nodes {
+			continue
+		}
+		mux.HandleFunc("POST "+prefix, s.authenticated(s.writable(s.handleCreate(collection))))
+		mux.HandleFunc("PUT "+prefix+"/{uid}", s.authenticated(s.writable(s.handleUpdate(collection))))
+		mux.HandleFunc("DELETE "+prefix+"/{uid}", s.authenticated(s.writable(s.handleDelete(collection))))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		latency := s.latency
+		fault := s.matchFault(r)
+		s.mu.Unlock()
+
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		if fault != nil {
+			for k, values := range fault.Header {
+				for _, v := range values {
+					w.Header().Add(k, v)
+				}
+			}
+			writeError(w, fault.Status, fault.Body)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// matchFault returns the first fault matching r, using one of its occurrences.
+func (s *Server) matchFault(r *http.Request) *Fault {
+	for i, fault := range s.faults {
+		if fault.Method != "" && fault.Method != r.Method {
+			continue
+		}
+		if fault.Path != "" && fault.Path != r.URL.Path {
+			continue
+		}
+
+		matched := *fault
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return &matched
+	}
+
+	return nil
+}
+
+type contextUser struct {
+	uid            int
+	managementRole string
+}
+
+// authenticated rejects requests without valid Basic or JWT credentials.
+func (s *Server) authenticated(next func(http.ResponseWriter, *http.Request, contextUser)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		user, ok := s.authenticate(r)
+		s.mu.Unlock()
+
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "invalid credentials")
+			return
+		}
+		if user.managementRole == "none" {
+			writeError(w, http.StatusForbidden, "user has no management role")
+			return
+		}
+
+		next(w, r, user)
+	}
+}
+
+// writable rejects changes requested by users that can't manage the cluster.
+func (s *Server) writable(next func(http.ResponseWriter, *http.Request, contextUser)) func(http.ResponseWriter, *http.Request, contextUser) {
+	return func(w http.ResponseWriter, r *http.Request, user contextUser) {
+		if !writeManagementRoles[user.managementRole] {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("management role %s cannot change objects", user.managementRole))
+			return
+		}
+		next(w, r, user)
+	}
+}
+
+func (s *Server) authenticate(r *http.Request) (contextUser, bool) {
+	scheme, credentials, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return contextUser{}, false
+		}
+		return s.checkPassword(username, password)
+	case "jwt", "bearer":
+		uid, ok := s.verifyToken(credentials)
+		if !ok {
+			return contextUser{}, false
+		}
+		user, ok := s.objects[Users][uid]
+		if !ok {
+			return contextUser{}, false
+		}
+		return s.contextUser(user), true
+	default:
+		return contextUser{}, false
+	}
+}
+
+func (s *Server) checkPassword(username, password string) (contextUser, bool) {
+	for uid, user := range s.objects[Users] {
+		if !strings.EqualFold(fmt.Sprint(user["email"]), username) && !strings.EqualFold(fmt.Sprint(user["name"]), username) {
+			continue
+		}
+		if user["status"] == "locked" || s.passwords[uid] != password {
+			return contextUser{}, false
+		}
+		return s.contextUser(user), true
+	}
+
+	return contextUser{}, false
+}
+
+// contextUser resolves the most privileged management role of a user.
+func (s *Server) contextUser(user Object) contextUser {
+	ranks := map[string]int{
+		"none": 0, "db_viewer": 1, "cluster_viewer": 2, "db_member": 3, "cluster_member": 4, "user_manager": 5, "admin": 6,
+	}
+
+	managementRole := "none"
+	if role, ok := user["role"].(string); ok && ranks[role] > ranks[managementRole] {
+		managementRole = role
+	}
+	if roleUIDs, ok := user["role_uids"].([]any); ok {
+		for _, v := range roleUIDs {
+			roleUID, err := toInt(v)
+			if err != nil {
+				continue
+			}
+			if role, ok := s.objects[Roles][roleUID]; ok {
+				if management, ok := role["management"].(string); ok && ranks[management] > ranks[managementRole] {
+					managementRole = management
+				}
+			}
+		}
+	}
+
+	return contextUser{uid: uidOf(user), managementRole: managementRole}
+}
+
+// handleAuthorize issues a JWT for the given credentials, see
+// https://redis.io/docs/latest/operate/rs/references/rest-api/requests/users/authorize/
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TTL      int    `json:"ttl"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.checkPassword(req.Username, req.Password)
+	if !ok {
+		s.addLog(Object{"type": "login_failed", "severity": "WARNING", "user_name": req.Username})
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+
+	ttl := time.Duration(req.TTL) * time.Second
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	writeJSON(w, http.StatusOK, Object{"access_token": s.signToken(user.uid, s.now().Add(ttl))})
+}
+
+func (s *Server) handleCluster(w http.ResponseWriter, _ *http.Request, _ contextUser) {
+	s.mu.Lock()
+	cluster := clone(s.cluster)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, cluster)
+}
+
+func (s *Server) handleLDAP(w http.ResponseWriter, _ *http.Request, _ contextUser) {
+	s.mu.Lock()
+	ldap := clone(s.ldap)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, ldap)
+}
+
+func (s *Server) handleCertificates(w http.ResponseWriter, _ *http.Request, _ contextUser) {
+	s.mu.Lock()
+	certs := clone(s.certs)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, certs)
+}
+
+// handleLogs serves the cluster event log filtered by stime and etime and
+// ordered by order, see
+// https://redis.io/docs/latest/operate/rs/references/rest-api/requests/logs/
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request, _ contextUser) {
+	query := r.URL.Query()
+
+	var stime, etime time.Time
+	for param, t := range map[string]*time.Time{"stime": &stime, "etime": &etime} {
+		if value := query.Get(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s: %s", param, err))
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	s.mu.Lock()
+	var logs []Object
+	for _, entry := range s.logs {
+		t, err := time.Parse(time.RFC3339, fmt.Sprint(entry["time"]))
+		if err != nil {
+			continue
+		}
+		if !stime.IsZero() && t.Before(stime) {
+			continue
+		}
+		if !etime.IsZero() && t.After(etime) {
+			continue
+		}
+		logs = append(logs, clone(entry))
+	}
+	s.mu.Unlock()
+
+	if query.Get("order") == "desc" {
+		for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+			logs[i], logs[j] = logs[j], logs[i]
+		}
+	}
+
+	if offset, err := strconv.Atoi(query.Get("offset")); err == nil && offset > 0 {
+		if offset > len(logs) {
+			offset = len(logs)
+		}
+		logs = logs[offset:]
+	}
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit >= 0 && limit < len(logs) {
+		logs = logs[:limit]
+	}
+
+	if logs == nil {
+		logs = []Object{}
+	}
+	writeJSON(w, http.StatusOK, logs)
+}
+
+func (s *Server) handleCRDBs(w http.ResponseWriter, _ *http.Request, _ contextUser) {
+	s.mu.Lock()
+	crdbs := make([]Object, 0, len(s.crdbs))
+	for _, crdb := range s.crdbs {
+		crdbs = append(crdbs, clone(crdb))
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, crdbs)
+}
+
+func (s *Server) handleCRDB(w http.ResponseWriter, r *http.Request, _ contextUser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, crdb := range s.crdbs {
+		if crdb["guid"] == r.PathValue("guid") {
+			writeJSON(w, http.StatusOK, clone(crdb))
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "not found")
+}
+
+func (s *Server) handleList(collection string) func(http.ResponseWriter, *http.Request, contextUser) {
+	return func(w http.ResponseWriter, _ *http.Request, _ contextUser) {
+		writeJSON(w, http.StatusOK, s.List(collection))
+	}
+}
+
+func (s *Server) handleGet(collection string) func(http.ResponseWriter, *http.Request, contextUser) {
+	return func(w http.ResponseWriter, r *http.Request, _ contextUser) {
+		uid, err := strconv.Atoi(r.PathValue("uid"))
+		if err != nil {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+
+		obj := s.Get(collection, uid)
+		if obj == nil {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, obj)
+	}
+}
+
+func (s *Server) handleCreate(collection string) func(http.ResponseWriter, *http.Request, contextUser) {
+	return func(w http.ResponseWriter, r *http.Request, user contextUser) {
+		var obj Object
+		if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		created, err := s.create(collection, obj)
+		if err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		s.addLog(changeLog(collection, "created", uidOf(created), user))
+
+		writeJSON(w, http.StatusOK, created)
+	}
+}
+
+func (s *Server) handleUpdate(collection string) func(http.ResponseWriter, *http.Request, contextUser) {
+	return func(w http.ResponseWriter, r *http.Request, user contextUser) {
+		uid, err := strconv.Atoi(r.PathValue("uid"))
+		if err != nil {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+
+		var fields Object
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		updated, err := s.update(collection, uid, fields)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.addLog(changeLog(collection, "updated", uid, user))
+
+		writeJSON(w, http.StatusOK, updated)
+	}
+}
+
+func (s *Server) handleDelete(collection string) func(http.ResponseWriter, *http.Request, contextUser) {
+	return func(w http.ResponseWriter, r *http.Request, user contextUser) {
+		uid, err := strconv.Atoi(r.PathValue("uid"))
+		if err != nil {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if _, ok := s.objects[collection][uid]; !ok {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		delete(s.objects[collection], uid)
+		if collection == Users {
+			delete(s.passwords, uid)
+		}
+		s.addLog(changeLog(collection, "deleted", uid, user))
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// changeLog returns the event log entry recorded for a change, e.g.
+// {"type": "user_created", "user_uid": 3}.
+func changeLog(collection, change string, uid int, actor contextUser) Object {
+	kind := map[string]string{
+		Users:        "user",
+		Roles:        "role",
+		Databases:    "bdb",
+		RedisACLs:    "redis_acl",
+		LDAPMappings: "ldap_mapping",
+	}[collection]
+
+	return Object{
+		"type":           kind + "_" + change,
+		kind + "_uid":    uid,
+		"originator_uid": actor.uid,
+	}
+}
+
+func (s *Server) signToken(uid int, expires time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]any{"uid": uid, "exp": expires.Unix()})
+	payload := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	mac := hmac.New(sha256.New, s.tokenSecret)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) verifyToken(token string) (int, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, false
+	}
+
+	mac := hmac.New(sha256.New, s.tokenSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return 0, false
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, false
+	}
+	var claims struct {
+		UID int   `json:"uid"`
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return 0, false
+	}
+	if s.now().Unix() > claims.Exp {
+		return 0, false
+	}
+
+	return claims.UID, true
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, description string) {
+	writeJSON(w, statusCode, Object{
+		"error_code":  strings.ToLower(strings.ReplaceAll(http.StatusText(statusCode), " ", "_")),
+		"description": description,
+	})
+}
@@ -0,0 +1,500 @@
+// Package fakeserver implements the subset of the Redis Enterprise REST API
+// used by the connector, backed by in-memory state. It is meant for tests and
+// local development: objects can be created, updated and deleted through the
+// API, requests are authenticated with Basic auth or a JWT obtained from
+// /v1/users/authorize, and faults and latency can be injected.
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Collections served by the fake cluster, by URL path segment.
+const (
+	Users        = "users"
+	Roles        = "roles"
+	Databases    = "bdbs"
+	RedisACLs    = "redis_acls"
+	LDAPMappings = "ldap_mappings"
+	Nodes        = "nodes"
+)
+
+var collections = []string{Users, Roles, Databases, RedisACLs, LDAPMappings, Nodes}
+
+// Object is a cluster object as sent and returned by the API.
+type Object = map[string]any
+
+// Fault makes the server answer matching requests with Status instead of
+// serving them. Method and Path are matched exactly, an empty value matches
+// everything. Times limits how many requests fail, 0 means until cleared.
+type Fault struct {
+	Method string
+	Path   string
+	Status int
+	Body   string
+	Header http.Header
+	Times  int
+}
+
+type Server struct {
+	mu          sync.Mutex
+	objects     map[string]map[int]Object
+	nextUID     map[string]int
+	passwords   map[int]string
+	cluster     Object
+	ldap        Object
+	certs       Object
+	crdbs       []Object
+	logs        []Object
+	faults      []*Fault
+	latency     time.Duration
+	tokenSecret []byte
+	now         func() time.Time
+	requests    []string
+}
+
+// New returns an empty fake cluster named cluster.local.
+func New() *Server {
+	s := &Server{
+		objects:     make(map[string]map[int]Object),
+		nextUID:     make(map[string]int),
+		passwords:   make(map[int]string),
+		cluster:     Object{"name": "cluster.local"},
+		ldap:        Object{"uris": []any{}, "control_plane": false, "data_plane": false},
+		certs:       Object{},
+		tokenSecret: []byte(fmt.Sprintf("fakeserver-%d", time.Now().UnixNano())),
+		now:         time.Now,
+	}
+	for _, collection := range collections {
+		s.objects[collection] = make(map[int]Object)
+		s.nextUID[collection] = 1
+	}
+
+	return s
+}
+
+// NewWithDefaults returns a fake cluster holding an admin user authenticated
+// with username and password, the default management roles, a database and a
+// Redis ACL.
+func NewWithDefaults(username, password string) *Server {
+	s := New()
+
+	adminRole := s.MustAdd(Roles, Object{"name": "Admin", "management": "admin"})
+	viewerRole := s.MustAdd(Roles, Object{"name": "Viewer", "management": "cluster_viewer"})
+	dbRole := s.MustAdd(Roles, Object{"name": "DB Member", "management": "db_member"})
+	acl := s.MustAdd(RedisACLs, Object{"name": "Full Access", "acl": "+@all ~*"})
+	readACL := s.MustAdd(RedisACLs, Object{"name": "Read Only", "acl": "+@read ~*"})
+
+	s.MustAddUser(Object{
+		"name":        "Admin",
+		"email":       username,
+		"role":        "admin",
+		"role_uids":   []any{adminRole},
+		"auth_method": "regular",
+	}, password)
+	s.MustAddUser(Object{
+		"name":        "Viewer",
+		"email":       "viewer@example.com",
+		"role":        "cluster_viewer",
+		"role_uids":   []any{viewerRole, dbRole},
+		"auth_method": "regular",
+	}, "viewer-password")
+
+	s.MustAdd(Databases, Object{
+		"name": "db1",
+		"roles_permissions": []any{
+			Object{"role_uid": adminRole, "redis_acl_uid": acl},
+			Object{"role_uid": dbRole, "redis_acl_uid": readACL},
+		},
+	})
+	s.MustAdd(Nodes, Object{"addr": "127.0.0.1", "status": "active"})
+
+	return s
+}
+
+// Seed is the content of a fake cluster, keyed by collection. Users can carry
+// a password to authenticate with.
+type Seed struct {
+	Cluster      Object              `json:"cluster"`
+	LDAP         Object              `json:"ldap"`
+	Certificates Object              `json:"certificates"`
+	Objects      map[string][]Object `json:"objects"`
+	CRDBs        []Object            `json:"crdbs"`
+	Logs         []Object            `json:"logs"`
+}
+
+// Load adds the content of a JSON encoded Seed to the fake cluster.
+func (s *Server) Load(r io.Reader) error {
+	var seed Seed
+	if err := json.NewDecoder(r).Decode(&seed); err != nil {
+		return fmt.Errorf("fakeserver: invalid seed: %w", err)
+	}
+
+	s.SetCluster(seed.Cluster)
+	s.SetLDAP(seed.LDAP)
+	s.SetCertificates(seed.Certificates)
+	for _, collection := range collections {
+		for _, obj := range seed.Objects[collection] {
+			if _, err := s.Add(collection, obj); err != nil {
+				return fmt.Errorf("fakeserver: invalid seed: %w", err)
+			}
+		}
+	}
+	for _, crdb := range seed.CRDBs {
+		s.AddCRDB(crdb)
+	}
+	for _, entry := range seed.Logs {
+		s.AddLog(entry)
+	}
+
+	return nil
+}
+
+// Add stores obj in collection, assigning it the next uid, and returns the uid.
+func (s *Server) Add(collection string, obj Object) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created, err := s.create(collection, obj)
+	if err != nil {
+		return 0, err
+	}
+
+	return uidOf(created), nil
+}
+
+// MustAdd is Add for seeding data, it panics on error.
+func (s *Server) MustAdd(collection string, obj Object) int {
+	uid, err := s.Add(collection, obj)
+	if err != nil {
+		panic(err)
+	}
+	return uid
+}
+
+// MustAddUser adds a user that can authenticate with password.
+func (s *Server) MustAddUser(obj Object, password string) int {
+	obj = clone(obj)
+	obj["password"] = password
+	return s.MustAdd(Users, obj)
+}
+
+// Get returns a copy of an object, or nil when it doesn't exist.
+func (s *Server) Get(collection string, uid int) Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[collection][uid]
+	if !ok {
+		return nil
+	}
+	return clone(obj)
+}
+
+// List returns a copy of every object of a collection, ordered by uid.
+func (s *Server) List(collection string) []Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(collection)
+}
+
+// Update merges fields into an existing object.
+func (s *Server) Update(collection string, uid int, fields Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.update(collection, uid, fields)
+	return err
+}
+
+// SetCluster merges fields into the object returned by /v1/cluster.
+func (s *Server) SetCluster(fields Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, v := range fields {
+		s.cluster[k] = v
+	}
+}
+
+// SetLDAP merges fields into the object returned by /v1/cluster/ldap.
+func (s *Server) SetLDAP(fields Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, v := range fields {
+		s.ldap[k] = v
+	}
+}
+
+// SetCertificates merges certificates and keys, keyed by name such as
+// proxy_cert, into the object returned by /v1/cluster/certificates.
+func (s *Server) SetCertificates(certs Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, v := range certs {
+		s.certs[k] = v
+	}
+}
+
+// AddCRDB adds an Active-Active database, identified by its guid, to the
+// ones returned by /v1/crdbs.
+func (s *Server) AddCRDB(crdb Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.crdbs = append(s.crdbs, normalize(crdb))
+}
+
+// AddLog appends an entry to the cluster event log. Entries without a time
+// are stamped with the current time.
+func (s *Server) AddLog(entry Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addLog(entry)
+}
+
+// Logs returns a copy of the cluster event log, oldest first.
+func (s *Server) Logs() []Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	logs := make([]Object, 0, len(s.logs))
+	for _, entry := range s.logs {
+		logs = append(logs, clone(entry))
+	}
+	return logs
+}
+
+// TruncateLogs drops the event log entries older than before, the way the
+// cluster rotates its log.
+func (s *Server) TruncateLogs(before time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kept []Object
+	for _, entry := range s.logs {
+		if t, err := time.Parse(time.RFC3339, fmt.Sprint(entry["time"])); err == nil && t.Before(before) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	s.logs = kept
+}
+
+// InjectFault makes matching requests fail until the fault is used up or
+// ClearFaults is called.
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &fault)
+}
+
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = d
+}
+
+// SetClock replaces the clock used to stamp log entries and tokens.
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+// Requests returns the "METHOD /path" of every request served so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// Start serves the fake cluster on a local port until the returned server is
+// closed. Every started server shares the state of s, like the nodes of a
+// cluster do.
+func (s *Server) Start() *httptest.Server {
+	return httptest.NewServer(s.Handler())
+}
+
+// StartTLS is Start with a self-signed certificate, use the returned server's
+// Client to talk to it.
+func (s *Server) StartTLS() *httptest.Server {
+	return httptest.NewTLSServer(s.Handler())
+}
+
+func (s *Server) create(collection string, obj Object) (Object, error) {
+	objects, ok := s.objects[collection]
+	if !ok {
+		return nil, fmt.Errorf("unknown collection %s", collection)
+	}
+
+	obj = normalize(obj)
+	if name, ok := obj["name"].(string); ok && collection != Nodes {
+		for _, existing := range objects {
+			if existing["name"] == name {
+				return nil, fmt.Errorf("%s named %s already exists", collection, name)
+			}
+		}
+	}
+
+	uid := s.nextUID[collection]
+	if requested, ok := obj["uid"]; ok {
+		if n, err := toInt(requested); err == nil && n > 0 {
+			if _, taken := objects[n]; taken {
+				return nil, fmt.Errorf("%s %d already exists", collection, n)
+			}
+			uid = n
+		}
+	}
+	if uid >= s.nextUID[collection] {
+		s.nextUID[collection] = uid + 1
+	}
+	obj["uid"] = uid
+
+	if collection == Users {
+		if password, ok := obj["password"].(string); ok {
+			s.passwords[uid] = password
+		}
+		delete(obj, "password")
+		if _, ok := obj["status"]; !ok {
+			obj["status"] = "active"
+		}
+		if _, ok := obj["password_issue_date"]; !ok {
+			obj["password_issue_date"] = s.now().UTC().Format(time.RFC3339)
+		}
+	}
+
+	objects[uid] = obj
+	return clone(obj), nil
+}
+
+func (s *Server) update(collection string, uid int, fields Object) (Object, error) {
+	obj, ok := s.objects[collection][uid]
+	if !ok {
+		return nil, errNotFound
+	}
+
+	for k, v := range normalize(fields) {
+		switch k {
+		case "uid":
+			continue
+		case "password":
+			if collection == Users {
+				s.passwords[uid] = fmt.Sprint(v)
+				obj["password_issue_date"] = s.now().UTC().Format(time.RFC3339)
+				continue
+			}
+		}
+		obj[k] = v
+	}
+
+	return clone(obj), nil
+}
+
+func (s *Server) list(collection string) []Object {
+	objects := s.objects[collection]
+	uids := make([]int, 0, len(objects))
+	for uid := range objects {
+		uids = append(uids, uid)
+	}
+	sort.Ints(uids)
+
+	res := make([]Object, 0, len(uids))
+	for _, uid := range uids {
+		res = append(res, clone(objects[uid]))
+	}
+	return res
+}
+
+func (s *Server) addLog(entry Object) {
+	entry = clone(entry)
+	if _, ok := entry["time"]; !ok {
+		entry["time"] = s.now().UTC().Format(time.RFC3339)
+	}
+	if _, ok := entry["severity"]; !ok {
+		entry["severity"] = "INFO"
+	}
+	s.logs = append(s.logs, entry)
+}
+
+// normalize round-trips obj through JSON so numbers and nested values have the
+// same types whether they were seeded from Go or decoded from a request.
+func normalize(obj Object) Object {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return clone(obj)
+	}
+
+	res := Object{}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&res); err != nil {
+		return clone(obj)
+	}
+
+	for k, v := range res {
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				res[k] = int(i)
+			} else if f, err := n.Float64(); err == nil {
+				res[k] = f
+			}
+		}
+	}
+
+	return res
+}
+
+func clone(obj Object) Object {
+	res := make(Object, len(obj))
+	for k, v := range obj {
+		res[k] = v
+	}
+	return res
+}
+
+func uidOf(obj Object) int {
+	uid, _ := toInt(obj["uid"])
+	return uid
+}
+
+func toInt(v any) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case float64:
+		return int(n), nil
+	case json.Number:
+		i, err := n.Int64()
+		return int(i), err
+	case string:
+		return strconv.Atoi(n)
+	default:
+		return 0, fmt.Errorf("not a number: %v", v)
+	}
+}
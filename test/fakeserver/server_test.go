@@ -0,0 +1,184 @@
+package fakeserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	adminEmail    = "admin@example.com"
+	adminPassword = "password"
+)
+
+func do(t *testing.T, req *http.Request, res any) int {
+	t.Helper()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if res != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+			t.Fatalf("Expected a JSON body, got %v", err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func newRequest(t *testing.T, method, url string, body any) *http.Request {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(adminEmail, adminPassword)
+	return req
+}
+
+func TestServer_Authentication(t *testing.T) {
+	server := NewWithDefaults(adminEmail, adminPassword)
+	node := server.Start()
+	defer node.Close()
+
+	req := newRequest(t, http.MethodGet, node.URL+"/v1/users", nil)
+	req.SetBasicAuth(adminEmail, "wrong")
+	if status := do(t, req, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong password, got %d", status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	req = newRequest(t, http.MethodPost, node.URL+"/v1/users/authorize", Object{"username": adminEmail, "password": adminPassword})
+	if status := do(t, req, &token); status != http.StatusOK || token.AccessToken == "" {
+		t.Fatalf("Expected a token, got %d", status)
+	}
+
+	req = newRequest(t, http.MethodGet, node.URL+"/v1/users", nil)
+	req.Header.Set("Authorization", "JWT "+token.AccessToken)
+	var users []Object
+	if status := do(t, req, &users); status != http.StatusOK || len(users) != 2 {
+		t.Errorf("Expected 2 users with a JWT, got %d and %d users", status, len(users))
+	}
+
+	req.Header.Set("Authorization", "JWT "+token.AccessToken+"x")
+	if status := do(t, req, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a tampered JWT, got %d", status)
+	}
+
+	req = newRequest(t, http.MethodDelete, node.URL+"/v1/users/1", nil)
+	req.SetBasicAuth("viewer@example.com", "viewer-password")
+	if status := do(t, req, nil); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a viewer deleting a user, got %d", status)
+	}
+}
+
+func TestServer_CRUD(t *testing.T) {
+	server := NewWithDefaults(adminEmail, adminPassword)
+	node := server.Start()
+	defer node.Close()
+
+	var created Object
+	req := newRequest(t, http.MethodPost, node.URL+"/v1/users", Object{"name": "New", "email": "new@example.com", "password": "secret", "role": "db_viewer"})
+	if status := do(t, req, &created); status != http.StatusOK {
+		t.Fatalf("Expected the user to be created, got %d", status)
+	}
+	if _, ok := created["password"]; ok {
+		t.Error("Expected the password not to be returned")
+	}
+	uid := uidOf(created)
+
+	req = newRequest(t, http.MethodPost, node.URL+"/v1/users", Object{"name": "New", "email": "other@example.com"})
+	if status := do(t, req, nil); status != http.StatusConflict {
+		t.Errorf("Expected 409 for a duplicate name, got %d", status)
+	}
+
+	req = newRequest(t, http.MethodPut, node.URL+"/v1/users/"+strconv.Itoa(uid), Object{"role": "admin"})
+	if status := do(t, req, nil); status != http.StatusOK {
+		t.Fatalf("Expected the user to be updated, got %d", status)
+	}
+	if role := server.Get(Users, uid)["role"]; role != "admin" {
+		t.Errorf("Expected the role to be updated, got %v", role)
+	}
+
+	req = newRequest(t, http.MethodDelete, node.URL+"/v1/users/"+strconv.Itoa(uid), nil)
+	if status := do(t, req, nil); status != http.StatusOK {
+		t.Fatalf("Expected the user to be deleted, got %d", status)
+	}
+	req = newRequest(t, http.MethodGet, node.URL+"/v1/users/"+strconv.Itoa(uid), nil)
+	if status := do(t, req, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted user, got %d", status)
+	}
+
+	var types []string
+	for _, entry := range server.Logs() {
+		types = append(types, entry["type"].(string))
+	}
+	if strings.Join(types, ",") != "user_created,user_updated,user_deleted" {
+		t.Errorf("Unexpected event log: %v", types)
+	}
+}
+
+func TestServer_Logs(t *testing.T) {
+	server := New()
+	server.MustAddUser(Object{"name": "Admin", "email": adminEmail, "role": "admin"}, adminPassword)
+	start := time.Date(2025, 2, 25, 13, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		server.AddLog(Object{"type": "event", "id": i, "time": start.Add(time.Duration(i) * time.Minute).Format(time.RFC3339)})
+	}
+
+	node := server.Start()
+	defer node.Close()
+
+	var logs []Object
+	req := newRequest(t, http.MethodGet, node.URL+"/v1/logs?order=desc&limit=2&stime="+start.Add(time.Minute).Format(time.RFC3339), nil)
+	if status := do(t, req, &logs); status != http.StatusOK {
+		t.Fatalf("Expected the logs, got %d", status)
+	}
+	if len(logs) != 2 || logs[0]["id"] != float64(4) || logs[1]["id"] != float64(3) {
+		t.Errorf("Unexpected logs: %v", logs)
+	}
+
+	server.TruncateLogs(start.Add(4 * time.Minute))
+	if len(server.Logs()) != 1 {
+		t.Errorf("Expected 1 log entry after truncation, got %d", len(server.Logs()))
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	server := NewWithDefaults(adminEmail, adminPassword)
+	node := server.Start()
+	defer node.Close()
+
+	server.InjectFault(Fault{Method: http.MethodGet, Path: "/v1/users", Status: http.StatusServiceUnavailable, Times: 2})
+
+	for i, expected := range []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK} {
+		if status := do(t, newRequest(t, http.MethodGet, node.URL+"/v1/users", nil), nil); status != expected {
+			t.Errorf("Request %d: expected %d, got %d", i, expected, status)
+		}
+	}
+
+	server.SetLatency(50 * time.Millisecond)
+	started := time.Now()
+	do(t, newRequest(t, http.MethodGet, node.URL+"/v1/roles", nil), nil)
+	if elapsed := time.Since(started); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the response to be delayed, took %v", elapsed)
+	}
+}
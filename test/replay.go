@@ -0,0 +1,113 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/conductorone/baton-redis/pkg/client"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+)
+
+// FixturesDir is where fixtures recorded with --record-fixtures are kept, one
+// directory per cluster version.
+const FixturesDir = "../../test/fixtures"
+
+// ReplayTransport answers requests with the fixtures recorded by
+// client.RecordingTransport. Requests without a fixture fail, so a test never
+// silently talks to a real cluster.
+type ReplayTransport struct {
+	fixtures map[string]client.Fixture
+}
+
+// NewReplayTransport loads every fixture in dir.
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &ReplayTransport{fixtures: make(map[string]client.Fixture, len(files))}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var fixture client.Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", file, err)
+		}
+
+		name := client.FixtureName(fixture.Request.Method, fixture.Request.Path, fixture.Request.Query)
+		t.fixtures[name] = fixture
+	}
+
+	return t, nil
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name := client.FixtureName(req.Method, req.URL.Path, req.URL.RawQuery)
+	fixture, ok := t.fixtures[name]
+	if !ok {
+		return nil, fmt.Errorf("no fixture recorded for %s %s", req.Method, req.URL.RequestURI())
+	}
+
+	body := []byte(fixture.Response.Text)
+	if len(fixture.Response.Body) > 0 {
+		body = fixture.Response.Body
+	}
+
+	header := make(http.Header, len(fixture.Response.Header))
+	for key, value := range fixture.Response.Header {
+		header.Set(key, value)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Response.Status, http.StatusText(fixture.Response.Status)),
+		StatusCode:    fixture.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// FixtureVersions returns the cluster versions fixtures were recorded for.
+func FixtureVersions() ([]string, error) {
+	entries, err := os.ReadDir(FixturesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			versions = append(versions, entry.Name())
+		}
+	}
+	sort.Strings(versions)
+
+	return versions, nil
+}
+
+// NewReplayClient returns a client answered by the fixtures recorded for a
+// cluster version.
+func NewReplayClient(version string) (*client.RedisClient, error) {
+	transport, err := NewReplayTransport(filepath.Join(FixturesDir, version))
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := uhttp.NewBaseHttpClient(&http.Client{Transport: transport})
+	return client.NewClient("admin@example.com", "password", "https://cluster.example.com", "9443", httpClient), nil
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/dotc1z"
+	sdkSync "github.com/conductorone/baton-sdk/pkg/sync"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// SyncContents is everything a sync wrote to a c1z file.
+type SyncContents struct {
+	Resources    []*v2.Resource
+	Entitlements []*v2.Entitlement
+	Grants       []*v2.Grant
+}
+
+type connectorClient struct {
+	v2.ResourceTypesServiceClient
+	v2.ResourcesServiceClient
+	v2.EntitlementsServiceClient
+	v2.GrantsServiceClient
+	v2.ConnectorServiceClient
+	v2.AssetServiceClient
+	v2.GrantManagerServiceClient
+	v2.ResourceManagerServiceClient
+	v2.AccountManagerServiceClient
+	v2.CredentialManagerServiceClient
+	v2.EventServiceClient
+	v2.TicketsServiceClient
+}
+
+// Serve exposes connector over gRPC on a local port, the way baton runs
+// connectors, and returns a client for it.
+func Serve(t *testing.T, connector types.ConnectorServer) types.ConnectorClient {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	server := grpc.NewServer()
+	v2.RegisterResourceTypesServiceServer(server, connector)
+	v2.RegisterResourcesServiceServer(server, connector)
+	v2.RegisterEntitlementsServiceServer(server, connector)
+	v2.RegisterGrantsServiceServer(server, connector)
+	v2.RegisterConnectorServiceServer(server, connector)
+	v2.RegisterAssetServiceServer(server, connector)
+	v2.RegisterGrantManagerServiceServer(server, connector)
+	v2.RegisterResourceManagerServiceServer(server, connector)
+	v2.RegisterAccountManagerServiceServer(server, connector)
+	v2.RegisterCredentialManagerServiceServer(server, connector)
+	v2.RegisterEventServiceServer(server, connector)
+	v2.RegisterTicketsServiceServer(server, connector)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return &connectorClient{
+		ResourceTypesServiceClient:     v2.NewResourceTypesServiceClient(conn),
+		ResourcesServiceClient:         v2.NewResourcesServiceClient(conn),
+		EntitlementsServiceClient:      v2.NewEntitlementsServiceClient(conn),
+		GrantsServiceClient:            v2.NewGrantsServiceClient(conn),
+		ConnectorServiceClient:         v2.NewConnectorServiceClient(conn),
+		AssetServiceClient:             v2.NewAssetServiceClient(conn),
+		GrantManagerServiceClient:      v2.NewGrantManagerServiceClient(conn),
+		ResourceManagerServiceClient:   v2.NewResourceManagerServiceClient(conn),
+		AccountManagerServiceClient:    v2.NewAccountManagerServiceClient(conn),
+		CredentialManagerServiceClient: v2.NewCredentialManagerServiceClient(conn),
+		EventServiceClient:             v2.NewEventServiceClient(conn),
+		TicketsServiceClient:           v2.NewTicketsServiceClient(conn),
+	}
+}
+
+// Sync runs a full sync of connector with the SDK syncer into a c1z file in a
+// temporary directory and returns the path of the file.
+func Sync(ctx context.Context, t *testing.T, connector types.ConnectorServer) string {
+	t.Helper()
+
+	c1zPath := filepath.Join(t.TempDir(), "sync.c1z")
+	syncer, err := sdkSync.NewSyncer(ctx, Serve(t, connector), sdkSync.WithC1ZPath(c1zPath), sdkSync.WithTmpDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := syncer.Sync(ctx); err != nil {
+		t.Fatalf("Expected the sync to succeed, got %v", err)
+	}
+	if err := syncer.Close(ctx); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	return c1zPath
+}
+
+// ReadC1Z returns the resources, entitlements and grants of the last sync
+// stored in a c1z file, each sorted by id.
+func ReadC1Z(ctx context.Context, t *testing.T, c1zPath string) *SyncContents {
+	t.Helper()
+
+	f, err := dotc1z.NewC1ZFile(ctx, c1zPath, dotc1z.WithTmpDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer f.Close()
+
+	contents := &SyncContents{}
+
+	pageToken := ""
+	for {
+		resp, err := f.ListResources(ctx, &v2.ResourcesServiceListResourcesRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		contents.Resources = append(contents.Resources, resp.List...)
+		if pageToken = resp.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+
+	for {
+		resp, err := f.ListEntitlements(ctx, &v2.EntitlementsServiceListEntitlementsRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		contents.Entitlements = append(contents.Entitlements, resp.List...)
+		if pageToken = resp.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+
+	for {
+		resp, err := f.ListGrants(ctx, &v2.GrantsServiceListGrantsRequest{PageToken: pageToken})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		contents.Grants = append(contents.Grants, resp.List...)
+		if pageToken = resp.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+
+	sort.Slice(contents.Resources, func(i, j int) bool {
+		return ResourceKey(contents.Resources[i].Id) < ResourceKey(contents.Resources[j].Id)
+	})
+	sort.Slice(contents.Entitlements, func(i, j int) bool { return contents.Entitlements[i].Id < contents.Entitlements[j].Id })
+	sort.Slice(contents.Grants, func(i, j int) bool { return contents.Grants[i].Id < contents.Grants[j].Id })
+
+	return contents
+}
+
+// ResourceKey formats a resource id as "type:id".
+func ResourceKey(id *v2.ResourceId) string {
+	return id.ResourceType + ":" + id.Resource
+}
+
+// V1Identifier returns the id carried by the V1Identifier annotation of a
+// grant, or an empty string when it has none.
+func V1Identifier(grant *v2.Grant) string {
+	annos := annotations.Annotations(grant.Annotations)
+	v1Identifier := &v2.V1Identifier{}
+	ok, err := annos.Pick(v1Identifier)
+	if err != nil || !ok {
+		return ""
+	}
+	return v1Identifier.Id
+}